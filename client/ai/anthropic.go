@@ -0,0 +1,298 @@
+package ai
+
+import (
+	"ai-stream-bot/config"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	anthropicVersion       = "2023-06-01"
+	anthropicDefaultAPIURL = "https://api.anthropic.com/v1"
+	// anthropicMinThinkingBudget Messages API 要求的最小思考预算
+	anthropicMinThinkingBudget = 1024
+)
+
+type AnthropicClient struct {
+	cfg        *config.AnthropicConfig
+	httpClient *http.Client
+}
+
+// anthropicMessage Messages API 的消息结构，system 角色需单独传递
+// Content 为字符串或 anthropicContentBlock 数组
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+// anthropicContentBlock 多模态内容块
+type anthropicContentBlock struct {
+	Type   string                `json:"type"`
+	Text   string                `json:"text,omitempty"`
+	Source *anthropicImageSource `json:"source,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+type anthropicThinking struct {
+	Type         string `json:"type"`
+	BudgetTokens int    `json:"budget_tokens"`
+}
+
+// anthropicRequest Messages API 请求体
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	MaxTokens int                `json:"max_tokens"`
+	Stream    bool               `json:"stream"`
+	Thinking  *anthropicThinking `json:"thinking,omitempty"`
+	// 未配置的生成参数不发送
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+}
+
+// anthropicCitation 引用信息，不同类型的引用只会填充部分字段
+type anthropicCitation struct {
+	Type          string `json:"type"`
+	CitedText     string `json:"cited_text"`
+	URL           string `json:"url"`
+	Title         string `json:"title"`
+	DocumentTitle string `json:"document_title"`
+}
+
+// anthropicUsage Messages API 用量，message_start 返回输入，message_delta 返回累计输出
+type anthropicUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// anthropicStreamEvent 流式事件，按 type 区分含义
+type anthropicStreamEvent struct {
+	Type    string `json:"type"`
+	Message struct {
+		Usage *anthropicUsage `json:"usage"`
+	} `json:"message"`
+	Usage *anthropicUsage `json:"usage"`
+	Delta struct {
+		Type       string             `json:"type"`
+		Text       string             `json:"text"`
+		Thinking   string             `json:"thinking"`
+		Citation   *anthropicCitation `json:"citation"`
+		StopReason string             `json:"stop_reason"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewAnthropicClient(cfg *config.AnthropicConfig) *AnthropicClient {
+	return &AnthropicClient{cfg: cfg, httpClient: http.DefaultClient}
+}
+
+func (c *AnthropicClient) GetProvider() Provider {
+	return ProviderAnthropic
+}
+
+func (c *AnthropicClient) GetModels() []string {
+	return configuredModels(c.cfg.Model, c.cfg.Models)
+}
+
+func (c *AnthropicClient) SupportsVision(model string) bool {
+	return slices.Contains(c.cfg.VisionModels, model)
+}
+
+func (c *AnthropicClient) StreamChat(ctx context.Context, req *AiChatStreamRequest) error {
+	body, err := json.Marshal(c.buildRequest(modelOrDefault(req, c.cfg.Model), req.Msgs, c.GetGeneration().Merge(req.Params)))
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.messagesURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("x-api-key", c.cfg.APIKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		hlog.Errorf("anthropic messages request error: %v", err)
+		return wrapRequestError(ctx, string(ProviderAnthropic), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		event := anthropicStreamEvent{}
+		if err := json.Unmarshal(raw, &event); err == nil && event.Error.Message != "" {
+			return newAPIError(string(ProviderAnthropic), resp.StatusCode, event.Error.Type, event.Error.Message)
+		}
+		return newAPIError(string(ProviderAnthropic), resp.StatusCode, "", strings.TrimSpace(string(raw)))
+	}
+
+	usage := &Usage{}
+	req.Usage = usage
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			// event: 行与 data 中的 type 一致，直接以 data 为准
+			continue
+		}
+		event := anthropicStreamEvent{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+			hlog.Errorf("unmarshal anthropic event error: %v, data: %s", err, data)
+			return err
+		}
+
+		switch event.Type {
+		case "message_start":
+			if event.Message.Usage != nil {
+				usage.PromptTokens = event.Message.Usage.InputTokens
+				usage.CompletionTokens = event.Message.Usage.OutputTokens
+			}
+		case "message_delta":
+			// 输出 token 中包含思考 token，接口未单独返回思考用量
+			if event.Usage != nil {
+				usage.CompletionTokens = event.Usage.OutputTokens
+			}
+			usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+			req.FinishReason = event.Delta.StopReason
+			switch event.Delta.StopReason {
+			case "max_tokens":
+				req.FinishReason = FinishReasonLength
+			case "refusal":
+				req.FinishReason = FinishReasonContentFilter
+			}
+		case "content_block_delta":
+			switch event.Delta.Type {
+			case "thinking_delta":
+				err = req.emitThink(ctx, event.Delta.Thinking)
+			case "text_delta":
+				err = req.emitAnswer(ctx, event.Delta.Text)
+			case "citations_delta":
+				err = c.sendCitation(ctx, req, event.Delta.Citation)
+			}
+			if err != nil {
+				return err
+			}
+		case "message_stop":
+			return nil
+		case "error":
+			// 流中返回的错误没有状态码，按错误类型归类
+			return newAPIError(string(ProviderAnthropic), 0, event.Error.Type, event.Error.Message)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		hlog.Errorf("anthropic stream error: %v", err)
+		return wrapRequestError(ctx, string(ProviderAnthropic), err)
+	}
+	return nil
+}
+
+func (c *AnthropicClient) GetGeneration() config.GenerationConfig {
+	return withGenerationDefaults(c.cfg.Generation)
+}
+
+// buildRequest 将通用消息转换为 Messages API 请求，system 消息合并到顶层字段
+// presence_penalty、seed 和 reasoning_effort 接口不支持，会被忽略
+func (c *AnthropicClient) buildRequest(model string, msgs []AiMessage, params config.GenerationConfig) anthropicRequest {
+	var systems []string
+	messages := make([]anthropicMessage, 0, len(msgs))
+	for _, m := range msgs {
+		if m.Role == "system" {
+			systems = append(systems, m.Content)
+			continue
+		}
+		messages = append(messages, anthropicMessage{Role: m.Role, Content: anthropicContent(m)})
+	}
+
+	req := anthropicRequest{
+		Model:         model,
+		System:        strings.Join(systems, "\n\n"),
+		Messages:      messages,
+		MaxTokens:     params.MaxTokens,
+		Stream:        true,
+		StopSequences: params.Stop,
+	}
+	if c.cfg.ThinkingBudget > 0 {
+		budget := max(c.cfg.ThinkingBudget, anthropicMinThinkingBudget)
+		// budget_tokens 必须小于 max_tokens
+		if budget >= req.MaxTokens {
+			req.MaxTokens = budget + params.MaxTokens
+		}
+		req.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: budget}
+	} else {
+		// 开启思考时接口不允许修改 temperature 和 top_p
+		req.Temperature = params.Temperature
+		req.TopP = params.TopP
+	}
+	return req
+}
+
+// anthropicContent 转换消息内容，包含图片时使用内容块数组
+func anthropicContent(m AiMessage) any {
+	if len(m.Parts) == 0 {
+		return m.Content
+	}
+	blocks := make([]anthropicContentBlock, 0, len(m.Parts))
+	for _, p := range m.Parts {
+		switch p.Type {
+		case ContentPartText:
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: p.Text})
+		case ContentPartImage:
+			source := &anthropicImageSource{Type: "url", URL: p.ImageURL.URL}
+			if mediaType, data, ok := p.ImageData(); ok {
+				source = &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, anthropicContentBlock{Type: "image", Source: source})
+		}
+	}
+	return blocks
+}
+
+// sendCitation 将引用写入事件流，同一来源会被多次引用，由使用方去重
+func (c *AnthropicClient) sendCitation(ctx context.Context, req *AiChatStreamRequest, citation *anthropicCitation) error {
+	if citation == nil {
+		return nil
+	}
+	title := citation.Title
+	if title == "" {
+		title = citation.DocumentTitle
+	}
+	return req.emitReference(ctx, Reference{Title: title, URL: citation.URL, Snippet: citation.CitedText})
+}
+
+// messagesURL 拼接 Messages API 地址，api_url 形如 https://api.anthropic.com/v1
+func (c *AnthropicClient) messagesURL() string {
+	baseURL := strings.TrimRight(c.cfg.APIURL, "/")
+	if baseURL == "" {
+		baseURL = anthropicDefaultAPIURL
+	}
+	if strings.HasSuffix(baseURL, "/messages") {
+		return baseURL
+	}
+	return baseURL + "/messages"
+}
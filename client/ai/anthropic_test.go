@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"ai-stream-bot/config"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestAnthropicClient(server *httptest.Server) *AnthropicClient {
+	client := NewAnthropicClient(&config.AnthropicConfig{
+		APIKey: "sk-test",
+		Model:  "claude-test",
+		APIURL: server.URL + "/v1",
+	})
+	client.httpClient = server.Client()
+	return client
+}
+
+// anthropicEvents 拼接 SSE 事件，每个事件带 event: 行
+func anthropicEvents(events ...string) string {
+	var body strings.Builder
+	for _, data := range events {
+		kind := data[len(`{"type":"`):]
+		kind = kind[:strings.IndexByte(kind, '"')]
+		body.WriteString("event: " + kind + "\ndata: " + data + "\n\n")
+	}
+	return body.String()
+}
+
+func TestAnthropicStreamChat(t *testing.T) {
+	messageStart := `{"type":"message_start","message":{"usage":{"input_tokens":10,"output_tokens":1}}}`
+	tests := []struct {
+		name             string
+		body             string
+		wantThink        string
+		wantAnswer       string
+		wantRefs         []Reference
+		wantFinishReason string
+		wantUsage        *Usage
+	}{
+		{
+			name: "thinking, text and citations deltas",
+			body: anthropicEvents(
+				messageStart,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"想一"}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"想"}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"你好"}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","cited_text":"片段","url":"https://a.com","title":"A"}}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"citations_delta","citation":{"type":"char_location","cited_text":"文档片段","document_title":"文档"}}}`,
+				`{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"，世界"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20}}`,
+				`{"type":"message_stop"}`,
+			),
+			wantThink:  "想一想",
+			wantAnswer: "你好，世界",
+			wantRefs: []Reference{
+				{Title: "A", URL: "https://a.com", Snippet: "片段"},
+				{Title: "文档", Snippet: "文档片段"},
+			},
+			wantFinishReason: "end_turn",
+			wantUsage:        &Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30},
+		},
+		{
+			name: "max_tokens maps to length",
+			body: anthropicEvents(
+				messageStart,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"a"}}`,
+				`{"type":"message_delta","delta":{"stop_reason":"max_tokens"},"usage":{"output_tokens":5}}`,
+				`{"type":"message_stop"}`,
+			),
+			wantAnswer:       "a",
+			wantFinishReason: FinishReasonLength,
+			wantUsage:        &Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15},
+		},
+		{
+			name: "refusal maps to content_filter",
+			body: anthropicEvents(
+				messageStart,
+				`{"type":"message_delta","delta":{"stop_reason":"refusal"},"usage":{"output_tokens":2}}`,
+				`{"type":"message_stop"}`,
+			),
+			wantFinishReason: FinishReasonContentFilter,
+			wantUsage:        &Usage{PromptTokens: 10, CompletionTokens: 2, TotalTokens: 12},
+		},
+		{
+			name: "events after message_stop are ignored",
+			body: anthropicEvents(
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"a"}}`,
+				`{"type":"message_stop"}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"b"}}`,
+			) + ": ping\n\n",
+			wantAnswer: "a",
+			wantUsage:  &Usage{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStreamServer(t, "/v1/messages", "text/event-stream", http.StatusOK, tt.body, nil)
+			req := &AiChatStreamRequest{Msgs: []AiMessage{{Role: "user", Content: "hi"}}}
+			result := runStreamChat(newTestAnthropicClient(server), req)
+			if result.err != nil {
+				t.Fatalf("StreamChat() error = %v", result.err)
+			}
+			if result.think != tt.wantThink {
+				t.Errorf("think = %q, want %q", result.think, tt.wantThink)
+			}
+			if result.answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", result.answer, tt.wantAnswer)
+			}
+			if !reflect.DeepEqual(result.refs, tt.wantRefs) {
+				t.Errorf("refs = %+v, want %+v", result.refs, tt.wantRefs)
+			}
+			if req.FinishReason != tt.wantFinishReason {
+				t.Errorf("FinishReason = %q, want %q", req.FinishReason, tt.wantFinishReason)
+			}
+			if !reflect.DeepEqual(req.Usage, tt.wantUsage) {
+				t.Errorf("Usage = %+v, want %+v", req.Usage, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestAnthropicStreamChatRequest(t *testing.T) {
+	var requestBody string
+	body := anthropicEvents(`{"type":"message_stop"}`)
+	server := newStreamServer(t, "/v1/messages", "text/event-stream", http.StatusOK, body, &requestBody)
+	req := &AiChatStreamRequest{
+		Msgs:  []AiMessage{{Role: "system", Content: "sys"}, {Role: "user", Content: "hi"}},
+		Model: "claude-other",
+	}
+	if result := runStreamChat(newTestAnthropicClient(server), req); result.err != nil {
+		t.Fatalf("StreamChat() error = %v", result.err)
+	}
+	for _, want := range []string{`"model":"claude-other"`, `"system":"sys"`, `"stream":true`} {
+		if !strings.Contains(requestBody, want) {
+			t.Errorf("request body %s does not contain %s", requestBody, want)
+		}
+	}
+	if strings.Contains(requestBody, `"role":"system"`) {
+		t.Errorf("request body %s contains system message", requestBody)
+	}
+}
+
+func TestAnthropicStreamChatError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantKind   error
+		wantStatus int
+		wantCode   string
+		wantAnswer string
+	}{
+		{
+			name:   "error event in stream",
+			status: http.StatusOK,
+			body: anthropicEvents(
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"a"}}`,
+				`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			),
+			wantKind:   ErrUpstream5xx,
+			wantCode:   "overloaded_error",
+			wantAnswer: "a",
+		},
+		{
+			name:       "error response",
+			status:     http.StatusTooManyRequests,
+			body:       `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your rate limit"}}`,
+			wantKind:   ErrRateLimited,
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   "rate_limit_error",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStreamServer(t, "/v1/messages", "text/event-stream", tt.status, tt.body, nil)
+			result := runStreamChat(newTestAnthropicClient(server), &AiChatStreamRequest{})
+			if !errors.Is(result.err, tt.wantKind) {
+				t.Fatalf("StreamChat() error = %v, want %v", result.err, tt.wantKind)
+			}
+			var apiErr *Error
+			if !errors.As(result.err, &apiErr) {
+				t.Fatalf("StreamChat() error = %T, want *Error", result.err)
+			}
+			if apiErr.StatusCode != tt.wantStatus || apiErr.Code != tt.wantCode {
+				t.Errorf("error = %+v", apiErr)
+			}
+			if result.answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", result.answer, tt.wantAnswer)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// Embedder 支持文本向量化的客户端，用于知识库检索
+type Embedder interface {
+	// Embed 返回与 texts 一一对应的向量
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// GetEmbedder 获取指定名称客户端的向量化能力，name 为空时使用默认客户端
+func (m *Manager) GetEmbedder(name string) (Embedder, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	client := m.defaultClient
+	if name != "" {
+		client = m.clients[name]
+	}
+	if client == nil {
+		return nil, fmt.Errorf("client %s not registered", name)
+	}
+	embedder, ok := client.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("client %s does not support embeddings", name)
+	}
+	return embedder, nil
+}
+
+// checkEmbeddings 校验返回的向量数量
+func checkEmbeddings(vectors [][]float32, texts []string) ([][]float32, error) {
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embeddings count mismatch: got %d, want %d", len(vectors), len(texts))
+	}
+	return vectors, nil
+}
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+)
+
+// 模型服务错误的分类，通过 errors.Is 判断
+var (
+	ErrRateLimited     = errors.New("rate limited")
+	ErrQuotaExceeded   = errors.New("quota exceeded")
+	ErrAuthFailed      = errors.New("auth failed")
+	ErrContextTooLong  = errors.New("context too long")
+	ErrContentFiltered = errors.New("content filtered")
+	ErrTimeout         = errors.New("timeout")
+	ErrUpstream5xx     = errors.New("upstream 5xx")
+)
+
+// Error 模型服务返回的错误，Kind 为上面的分类之一，无法归类时为空
+type Error struct {
+	Kind       error
+	Provider   string
+	StatusCode int
+	Code       string
+	Message    string
+	Err        error
+}
+
+func (e *Error) Error() string {
+	var msg strings.Builder
+	msg.WriteString(e.Provider + " api error")
+	if e.StatusCode > 0 {
+		msg.WriteString(fmt.Sprintf(": status %d", e.StatusCode))
+	}
+	if e.Code != "" {
+		msg.WriteString(", code " + e.Code)
+	}
+	if e.Message != "" {
+		msg.WriteString(", message: " + e.Message)
+	}
+	if e.Err != nil {
+		msg.WriteString(": " + e.Err.Error())
+	}
+	return msg.String()
+}
+
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && e.Kind == target
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// 错误码和错误信息中的关键词，覆盖 OpenAI 兼容接口、Anthropic、Ollama 和方舟的常见返回
+var (
+	quotaKeywords    = []string{"quota", "insufficient", "billing", "balance", "overdue", "credit"}
+	authKeywords     = []string{"authentication", "unauthorized", "invalid_api_key", "invalid api key", "permission", "accessdenied"}
+	contextKeywords  = []string{"context_length", "context length", "context window", "maximum context", "too long", "too many tokens", "exceed max message tokens", "exceeds the maximum"}
+	filteredKeywords = []string{"content_filter", "content filter", "sensitive", "content_policy", "safety", "risk"}
+	overloadKeywords = []string{"overloaded", "server_error", "internal_error", "internalservice"}
+)
+
+// newAPIError 归类模型服务的错误，优先按 HTTP 状态码判断
+// 只有流中返回的错误（状态码为 0）和 400 才按错误码和错误信息中的关键词归类
+func newAPIError(provider string, statusCode int, code, message string) *Error {
+	e := &Error{Provider: provider, StatusCode: statusCode, Code: code, Message: message}
+	text := strings.ToLower(code + " " + message)
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		e.Kind = ErrAuthFailed
+	case statusCode == http.StatusPaymentRequired:
+		e.Kind = ErrQuotaExceeded
+	case statusCode == http.StatusTooManyRequests:
+		// OpenAI 余额不足时同样返回 429，错误码为 insufficient_quota
+		e.Kind = ErrRateLimited
+		if strings.Contains(text, "quota") || strings.Contains(text, "billing") {
+			e.Kind = ErrQuotaExceeded
+		}
+	case statusCode == http.StatusRequestTimeout || statusCode == http.StatusGatewayTimeout:
+		e.Kind = ErrTimeout
+	case statusCode == http.StatusRequestEntityTooLarge:
+		e.Kind = ErrContextTooLong
+	case statusCode >= http.StatusInternalServerError:
+		e.Kind = ErrUpstream5xx
+	case statusCode == 0 || statusCode == http.StatusBadRequest:
+		e.Kind = classifyByKeywords(text)
+	}
+	return e
+}
+
+// classifyByKeywords 按错误码和错误信息中的关键词归类，无法归类时返回 nil
+func classifyByKeywords(text string) error {
+	switch {
+	case containsAny(text, filteredKeywords):
+		return ErrContentFiltered
+	case containsAny(text, contextKeywords):
+		return ErrContextTooLong
+	case containsAny(text, authKeywords):
+		return ErrAuthFailed
+	case containsAny(text, quotaKeywords):
+		return ErrQuotaExceeded
+	case strings.Contains(text, "rate_limit") || strings.Contains(text, "ratelimit"):
+		return ErrRateLimited
+	case containsAny(text, overloadKeywords):
+		return ErrUpstream5xx
+	}
+	return nil
+}
+
+// wrapRequestError 归类请求过程中的网络错误，ctx 取消时原样返回
+func wrapRequestError(ctx context.Context, provider string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) && ctx.Err() != nil {
+		return err
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return &Error{Kind: ErrTimeout, Provider: provider, Err: err}
+	}
+	return err
+}
+
+// wrapVolcError 归类方舟 SDK 返回的错误
+func wrapVolcError(ctx context.Context, err error) error {
+	var apiErr *model.APIError
+	if errors.As(err, &apiErr) {
+		return newAPIError(string(ProviderVolc), apiErr.HTTPStatusCode, apiErr.Code, apiErr.Message)
+	}
+	var reqErr *model.RequestError
+	if errors.As(err, &reqErr) && reqErr.HTTPStatusCode > 0 {
+		e := newAPIError(string(ProviderVolc), reqErr.HTTPStatusCode, "", "")
+		e.Err = reqErr.Err
+		return e
+	}
+	return wrapRequestError(ctx, string(ProviderVolc), err)
+}
+
+func containsAny(text string, keywords []string) bool {
+	for _, k := range keywords {
+		if strings.Contains(text, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		code    string
+		message string
+		want    error
+	}{
+		{name: "401", status: http.StatusUnauthorized, code: "invalid_api_key", message: "Incorrect API key provided", want: ErrAuthFailed},
+		{name: "403 insufficient permissions is auth", status: http.StatusForbidden, message: "insufficient permissions for this model", want: ErrAuthFailed},
+		{name: "402", status: http.StatusPaymentRequired, message: "payment required", want: ErrQuotaExceeded},
+		{name: "429 rate limit", status: http.StatusTooManyRequests, code: "rate_limit_exceeded", message: "Rate limit reached", want: ErrRateLimited},
+		{name: "429 insufficient quota", status: http.StatusTooManyRequests, code: "insufficient_quota", message: "You exceeded your current quota", want: ErrQuotaExceeded},
+		{name: "429 with risk wording is still rate limited", status: http.StatusTooManyRequests, message: "too many requests, risk control triggered", want: ErrRateLimited},
+		{name: "408", status: http.StatusRequestTimeout, want: ErrTimeout},
+		{name: "504", status: http.StatusGatewayTimeout, message: "upstream timeout", want: ErrTimeout},
+		{name: "413", status: http.StatusRequestEntityTooLarge, want: ErrContextTooLong},
+		{name: "500 with safety wording is upstream", status: http.StatusInternalServerError, message: "safety service unavailable", want: ErrUpstream5xx},
+		{name: "503 sensitive wording is upstream", status: http.StatusServiceUnavailable, message: "sensitive word service down", want: ErrUpstream5xx},
+		{name: "529 overloaded", status: 529, code: "overloaded_error", message: "Overloaded", want: ErrUpstream5xx},
+		{name: "400 content filter", status: http.StatusBadRequest, code: "SensitiveContentDetected", message: "The request failed because the input text may contain sensitive information.", want: ErrContentFiltered},
+		{name: "400 context length", status: http.StatusBadRequest, code: "context_length_exceeded", message: "This model's maximum context length is 8192 tokens", want: ErrContextTooLong},
+		{name: "400 insufficient permissions is auth", status: http.StatusBadRequest, message: "insufficient permissions", want: ErrAuthFailed},
+		{name: "400 unknown", status: http.StatusBadRequest, message: "invalid parameter temperature", want: nil},
+		{name: "stream error rate limit", status: 0, code: "rate_limit_error", message: "Number of requests has exceeded your rate limit", want: ErrRateLimited},
+		{name: "stream error overloaded", status: 0, code: "overloaded_error", message: "Overloaded", want: ErrUpstream5xx},
+		{name: "404 is not classified by keywords", status: http.StatusNotFound, message: "model not found, check your quota", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newAPIError("test", tt.status, tt.code, tt.message)
+			if err.Kind != tt.want {
+				t.Errorf("Kind = %v, want %v", err.Kind, tt.want)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("errors.Is(%v, %v) = false", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapRequestError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := wrapRequestError(canceled, "test", context.Canceled); err != context.Canceled {
+		t.Errorf("canceled request error = %v, want context.Canceled", err)
+	}
+	if err := wrapRequestError(context.Background(), "test", context.DeadlineExceeded); !errors.Is(err, ErrTimeout) {
+		t.Errorf("deadline error = %v, want ErrTimeout", err)
+	}
+}
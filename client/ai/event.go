@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+// EventKind 流式事件类型
+type EventKind string
+
+const (
+	EventThinkDelta  EventKind = "think_delta"
+	EventAnswerDelta EventKind = "answer_delta"
+	EventReference   EventKind = "reference"
+	EventToolCall    EventKind = "tool_call"
+	EventUsage       EventKind = "usage"
+	EventFinish      EventKind = "finish"
+	EventError       EventKind = "error"
+)
+
+// StreamEvent 流式事件，按 Kind 使用对应的字段
+//
+// 客户端只写入 ThinkDelta、AnswerDelta 和 Reference；ToolCall、Usage、Finish 和 Error 由 Manager 写入。
+// Manager.StreamEvents 返回的事件流以 Finish 或 Error 结束，随后关闭；
+// ctx 取消后剩余事件不再写入，事件流直接关闭。
+type StreamEvent struct {
+	Kind EventKind
+	// Text ThinkDelta、AnswerDelta 的增量文本
+	Text      string
+	Reference *Reference
+	ToolCall  *ToolCallEvent
+	Usage     *Usage
+	// Reason Finish 的结束原因，如 FinishReasonLength
+	Reason string
+	Err    error
+}
+
+// ToolCallEvent 工具调用过程，调用开始和结束时各写入一次，结束时 Done 为 true
+type ToolCallEvent struct {
+	Call   ToolCall
+	Done   bool
+	Result string
+	Err    error
+}
+
+// StreamEvents 使用指定名称的客户端发送聊天请求，name 为空时按故障转移顺序
+// 调用方需读取到事件流关闭，提前退出时需取消 ctx，客户端写入时会随之返回
+func (m *Manager) StreamEvents(ctx context.Context, name string, req *AiChatStreamRequest) <-chan StreamEvent {
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		req.Events = events
+		err := m.streamChatRecovered(ctx, name, req)
+		if req.Usage != nil {
+			_ = sendStream(ctx, events, StreamEvent{Kind: EventUsage, Usage: req.Usage})
+		}
+		if err != nil {
+			_ = sendStream(ctx, events, StreamEvent{Kind: EventError, Err: err})
+			return
+		}
+		_ = sendStream(ctx, events, StreamEvent{Kind: EventFinish, Reason: req.FinishReason})
+	}()
+	return events
+}
+
+// streamChatRecovered 将客户端或工具的 panic 转换为错误，避免整个进程退出
+func (m *Manager) streamChatRecovered(ctx context.Context, name string, req *AiChatStreamRequest) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			hlog.Errorf("stream chat panic: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("stream chat panic: %v", r)
+		}
+	}()
+	return m.StreamChatWith(ctx, name, req)
+}
+
+// emitThink 写入思考增量，空内容不写入
+func (r *AiChatStreamRequest) emitThink(ctx context.Context, text string) error {
+	if text == "" {
+		return nil
+	}
+	return sendStream(ctx, r.Events, StreamEvent{Kind: EventThinkDelta, Text: text})
+}
+
+// emitAnswer 写入回答增量，空内容不写入
+func (r *AiChatStreamRequest) emitAnswer(ctx context.Context, text string) error {
+	if text == "" {
+		return nil
+	}
+	return sendStream(ctx, r.Events, StreamEvent{Kind: EventAnswerDelta, Text: text})
+}
+
+func (r *AiChatStreamRequest) emitReference(ctx context.Context, ref Reference) error {
+	return sendStream(ctx, r.Events, StreamEvent{Kind: EventReference, Reference: &ref})
+}
+
+func (r *AiChatStreamRequest) emitToolCall(ctx context.Context, call *ToolCallEvent) error {
+	return sendStream(ctx, r.Events, StreamEvent{Kind: EventToolCall, ToolCall: call})
+}
@@ -0,0 +1,76 @@
+package ai
+
+import (
+	"ai-stream-bot/config"
+	"fmt"
+)
+
+// DefaultFactory 根据实例配置中的类型创建对应的客户端
+type DefaultFactory struct {
+}
+
+func NewFactory() Factory {
+	return &DefaultFactory{}
+}
+
+func (f *DefaultFactory) CreateClient(cfg *config.InstanceConfig) (Client, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("instance name is required")
+	}
+	switch Provider(cfg.Type) {
+	case ProviderOpenAI:
+		return NewOpenAIClient(&config.OpenAIConfig{
+			Enable:         cfg.Enable,
+			APIKey:         cfg.APIKey,
+			Model:          cfg.Model,
+			APIURL:         cfg.APIURL,
+			Models:         cfg.Models,
+			VisionModels:   cfg.VisionModels,
+			Generation:     cfg.Generation,
+			EmbeddingModel: cfg.EmbeddingModel,
+		}), nil
+	case ProviderVolc:
+		return NewVolcClient(&config.VolcConfig{
+			Enable:         cfg.Enable,
+			APIKey:         cfg.APIKey,
+			Model:          cfg.Model,
+			APIURL:         cfg.APIURL,
+			Mode:           cfg.Mode,
+			Models:         cfg.Models,
+			VisionModels:   cfg.VisionModels,
+			Generation:     cfg.Generation,
+			EmbeddingModel: cfg.EmbeddingModel,
+		}), nil
+	case ProviderAnthropic:
+		return NewAnthropicClient(&config.AnthropicConfig{
+			Enable:         cfg.Enable,
+			APIKey:         cfg.APIKey,
+			Model:          cfg.Model,
+			APIURL:         cfg.APIURL,
+			ThinkingBudget: cfg.ThinkingBudget,
+			Models:         cfg.Models,
+			VisionModels:   cfg.VisionModels,
+			Generation:     cfg.Generation,
+		}), nil
+	case ProviderOllama:
+		return NewOllamaClient(&config.OllamaConfig{
+			Enable:         cfg.Enable,
+			Model:          cfg.Model,
+			APIURL:         cfg.APIURL,
+			Models:         cfg.Models,
+			VisionModels:   cfg.VisionModels,
+			Generation:     cfg.Generation,
+			EmbeddingModel: cfg.EmbeddingModel,
+		}), nil
+	case ProviderMock:
+		return NewMockClient(&config.MockConfig{
+			Enable:       cfg.Enable,
+			Model:        cfg.Model,
+			Models:       cfg.Models,
+			VisionModels: cfg.VisionModels,
+			Fixture:      cfg.Fixture,
+		}), nil
+	default:
+		return nil, fmt.Errorf("instance %s has unknown type %s", cfg.Name, cfg.Type)
+	}
+}
@@ -0,0 +1,32 @@
+package ai
+
+import "ai-stream-bot/config"
+
+// GenerationClient 支持配置生成参数的客户端
+type GenerationClient interface {
+	// GetGeneration 配置的生成参数，已填充默认值
+	GetGeneration() config.GenerationConfig
+}
+
+// GetGeneration 获取指定客户端配置的生成参数，name 为空时使用默认客户端
+func (m *Manager) GetGeneration(name string) config.GenerationConfig {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	client := m.defaultClient
+	if name != "" {
+		client = m.clients[name]
+	}
+	if c, ok := client.(GenerationClient); ok {
+		return c.GetGeneration()
+	}
+	return withGenerationDefaults(config.GenerationConfig{})
+}
+
+// withGenerationDefaults 填充未配置的 max_tokens
+func withGenerationDefaults(g config.GenerationConfig) config.GenerationConfig {
+	if g.MaxTokens == 0 {
+		g.MaxTokens = MaxTokens
+	}
+	return g
+}
@@ -1,35 +1,71 @@
 package ai
 
 import (
+	"ai-stream-bot/config"
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 )
 
 // Provider 定义 AI 服务提供商类型
 type Provider string
 
 const (
-	ProviderOpenAI Provider = "openai"
-	ProviderVolc   Provider = "volc"
+	ProviderOpenAI    Provider = "openai"
+	ProviderVolc      Provider = "volc"
+	ProviderAnthropic Provider = "anthropic"
+	ProviderOllama    Provider = "ollama"
+	ProviderMock      Provider = "mock"
 )
 
 const (
 	MaxTokens = 8092
 )
 
+const (
+	// FinishReasonLength 输出达到 max_tokens 被截断
+	FinishReasonLength = "length"
+	// FinishReasonContentFilter 输出被内容安全策略拦截
+	FinishReasonContentFilter = "content_filter"
+)
+
 // AiMessage 定义消息结构
 type AiMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// ToolCalls assistant 消息中模型发起的工具调用
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	// ToolCallId tool 消息对应的工具调用 ID
+	ToolCallId string `json:"tool_call_id,omitempty"`
+	// Parts 多模态内容，包含图片时使用，Content 仍保留其中的文本
+	Parts []ContentPart `json:"-"`
 }
 
 type AiChatStreamRequest struct {
-	Ctx          context.Context
-	Msgs         []AiMessage `json:"msgs"`
-	ThinkStream  chan string `json:"think_stream"`
-	AnswerStream chan string `json:"answer_stream"`
-	RefStream    chan string `json:"ref_stream"`
+	Ctx  context.Context
+	Msgs []AiMessage `json:"msgs"`
+	// Model 覆盖客户端配置的模型，为空时使用配置中的默认模型
+	Model string `json:"model"`
+	// Events 流式事件，由 Manager.StreamEvents 创建和关闭，客户端只写入不关闭
+	Events chan StreamEvent `json:"-"`
+	// Tools 允许模型调用的工具，为空时不开启工具调用
+	Tools []ToolDefinition `json:"tools"`
+	// ToolCalls 本轮模型返回的工具调用，由客户端回填
+	ToolCalls []ToolCall `json:"tool_calls"`
+	// Usage token 用量，由客户端回填，多轮工具调用时由 Manager 累加
+	Usage *Usage `json:"usage"`
+	// ServedBy 实际完成本次请求的客户端实例名称，由 Manager 回填
+	ServedBy string `json:"served_by"`
+	// Params 会话中覆盖的生成参数，与客户端配置合并后生效，为空时使用配置
+	Params *config.GenerationConfig `json:"params"`
+	// FinishReason 模型结束输出的原因，由客户端回填，因长度截断时为 FinishReasonLength
+	FinishReason string `json:"finish_reason"`
 }
 
 // Client 定义 AI 客户端接口
@@ -37,13 +73,19 @@ type Client interface {
 	// GetProvider 获取服务提供商类型
 	GetProvider() Provider
 
+	// GetModels 获取可用模型列表，第一个为默认模型
+	GetModels() []string
+
 	StreamChat(ctx context.Context, req *AiChatStreamRequest) error
 }
 
-// Manager AI 客户端管理器
+// Manager AI 客户端管理器，客户端按实例名称注册
 type Manager struct {
-	clients       map[Provider]Client
+	clients       map[string]Client
+	defaultName   string
 	defaultClient Client
+	// fallbackChain 按顺序尝试的实例名称，为空时只使用 defaultClient
+	fallbackChain []string
 	mu            sync.RWMutex
 }
 
@@ -56,60 +98,339 @@ var (
 func GetManager() *Manager {
 	once.Do(func() {
 		manager = &Manager{
-			clients: make(map[Provider]Client),
+			clients: make(map[string]Client),
 		}
 	})
 	return manager
 }
 
-// RegisterClient 注册 AI 客户端
+// RegisterClient 注册 AI 客户端，以服务提供商类型作为实例名称
 func (m *Manager) RegisterClient(client Client) {
+	m.RegisterNamedClient(string(client.GetProvider()), client)
+}
+
+// RegisterNamedClient 以指定名称注册 AI 客户端，同一类型可注册多个实例
+func (m *Manager) RegisterNamedClient(name string, client Client) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.clients[client.GetProvider()] = client
+	m.clients[name] = client
+}
+
+// ClientInfo 已注册客户端的描述信息
+type ClientInfo struct {
+	Name      string
+	Provider  Provider
+	Models    []string
+	IsDefault bool
+}
+
+// ListClients 列出已注册的客户端，按名称排序
+func (m *Manager) ListClients() []ClientInfo {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	infos := make([]ClientInfo, 0, len(m.clients))
+	for name, client := range m.clients {
+		infos = append(infos, ClientInfo{
+			Name:      name,
+			Provider:  client.GetProvider(),
+			Models:    client.GetModels(),
+			IsDefault: name == m.defaultName,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
+
+// GetModel 获取指定客户端实际使用的模型，name 为空时使用默认客户端
+func (m *Manager) GetModel(name string, model string) string {
+	if model != "" {
+		return model
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	client := m.defaultClient
+	if name != "" {
+		client = m.clients[name]
+	}
+	if client == nil {
+		return ""
+	}
+	return firstModel(client)
+}
+
+// SupportsVision 指定客户端和模型是否支持图片输入，name 为空时使用默认客户端
+func (m *Manager) SupportsVision(name string, model string) bool {
+	model = m.GetModel(name, model)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	client := m.defaultClient
+	if name != "" {
+		client = m.clients[name]
+	}
+	return client != nil && supportsVision(client, model)
 }
 
 // SetDefaultClient 设置默认 AI 客户端
-func (m *Manager) SetDefaultClient(provider Provider) error {
+func (m *Manager) SetDefaultClient(name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	client, ok := m.clients[provider]
+	client, ok := m.clients[name]
 	if !ok {
-		return fmt.Errorf("provider %s not registered", provider)
+		return fmt.Errorf("client %s not registered", name)
 	}
 
+	m.defaultName = name
 	m.defaultClient = client
 	return nil
 }
 
-// GetClient 获取指定提供商的客户端
-func (m *Manager) GetClient(provider Provider) (Client, error) {
+// GetClient 获取指定名称的客户端
+func (m *Manager) GetClient(name string) (Client, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	client, ok := m.clients[provider]
+	client, ok := m.clients[name]
 	if !ok {
-		return nil, fmt.Errorf("provider %s not registered", provider)
+		return nil, fmt.Errorf("client %s not registered", name)
 	}
 	return client, nil
 }
 
-// Chat 使用默认客户端发送聊天请求
+// SetFallbackChain 设置故障转移顺序，第一个作为默认客户端
+func (m *Manager) SetFallbackChain(names ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range names {
+		if _, ok := m.clients[name]; !ok {
+			return fmt.Errorf("client %s not registered", name)
+		}
+	}
+	m.fallbackChain = names
+	if len(names) > 0 {
+		m.defaultName = names[0]
+		m.defaultClient = m.clients[names[0]]
+	}
+	return nil
+}
+
+// StreamChat 使用默认客户端发送聊天请求，配置了故障转移时按顺序重试
 func (m *Manager) StreamChat(ctx context.Context, req *AiChatStreamRequest) error {
+	return m.streamChatWithTools(ctx, req, m.streamChatFallback)
+}
+
+// StreamChatWith 使用指定名称的客户端发送聊天请求，name 为空时等同于 StreamChat
+func (m *Manager) StreamChatWith(ctx context.Context, name string, req *AiChatStreamRequest) error {
+	if name == "" {
+		return m.StreamChat(ctx, req)
+	}
+	return m.streamChatWithTools(ctx, req, func(ctx context.Context, req *AiChatStreamRequest) error {
+		return m.streamChatNamed(ctx, name, req)
+	})
+}
+
+// streamChatWithTools 执行工具调用循环，模型返回工具调用时执行工具并继续对话，直到得到最终回答
+func (m *Manager) streamChatWithTools(ctx context.Context, req *AiChatStreamRequest, stream func(context.Context, *AiChatStreamRequest) error) error {
+	if len(req.Tools) == 0 {
+		return stream(ctx, req)
+	}
+
+	turn := *req
+	turn.Msgs = slices.Clone(req.Msgs)
+	usage := &Usage{}
+	for round := 0; ; round++ {
+		turn.ToolCalls = nil
+		turn.Usage = nil
+		err := stream(ctx, &turn)
+		req.ServedBy = turn.ServedBy
+		req.FinishReason = turn.FinishReason
+		usage.Add(turn.Usage)
+		req.Usage = usage
+		if err != nil {
+			return err
+		}
+		if len(turn.ToolCalls) == 0 {
+			return nil
+		}
+		if round >= MaxToolRounds {
+			return fmt.Errorf("tool call rounds exceed %d", MaxToolRounds)
+		}
+
+		// 后续轮次固定使用首轮返回工具调用的客户端
+		servedBy := turn.ServedBy
+		stream = func(ctx context.Context, req *AiChatStreamRequest) error {
+			return m.streamChatNamed(ctx, servedBy, req)
+		}
+		turn.Msgs = append(turn.Msgs, AiMessage{Role: "assistant", ToolCalls: turn.ToolCalls})
+		for _, call := range turn.ToolCalls {
+			turn.Msgs = append(turn.Msgs, AiMessage{
+				Role:       "tool",
+				Content:    callTool(ctx, req, call),
+				ToolCallId: call.ID,
+			})
+		}
+	}
+}
+
+// callTool 执行工具调用并写入调用前后的事件，失败时将错误信息返回给模型
+func callTool(ctx context.Context, req *AiChatStreamRequest, call ToolCall) string {
+	_ = req.emitToolCall(ctx, &ToolCallEvent{Call: call})
+	result, err := GetToolRegistry().Call(ctx, call.Function.Name, call.Function.Arguments)
+	if err != nil {
+		hlog.Warnf("call tool %s error: %v", call.Function.Name, err)
+		_ = req.emitToolCall(ctx, &ToolCallEvent{Call: call, Done: true, Err: err})
+		return "error: " + err.Error()
+	}
+	_ = req.emitToolCall(ctx, &ToolCallEvent{Call: call, Done: true, Result: result})
+	return result
+}
+
+// streamChatFallback 按故障转移顺序发送聊天请求
+func (m *Manager) streamChatFallback(ctx context.Context, req *AiChatStreamRequest) error {
 	m.mu.RLock()
-	client := m.defaultClient
+	names := m.fallbackChain
+	if len(names) == 0 && m.defaultClient != nil {
+		names = []string{m.defaultName}
+	}
+	clients := make([]Client, 0, len(names))
+	for _, name := range names {
+		clients = append(clients, m.clients[name])
+	}
 	m.mu.RUnlock()
 
-	if client == nil {
+	if len(clients) == 0 {
 		return fmt.Errorf("no default client set")
 	}
+	if len(clients) == 1 {
+		req.ServedBy = names[0]
+		return streamChatClient(ctx, names[0], clients[0], req)
+	}
 
-	return client.StreamChat(ctx, req)
+	var errs []error
+	model := req.Model
+	for i, client := range clients {
+		// 路由或会话指定的模型属于首选服务，备用服务未提供该模型时使用自己的默认模型
+		req.Model = model
+		if i > 0 && model != "" && !slices.Contains(client.GetModels(), model) {
+			req.Model = firstModel(client)
+		}
+		// 包含图片时跳过不支持图片的客户端
+		if HasImages(req.Msgs) && !supportsVision(client, modelOrDefault(req, firstModel(client))) {
+			errs = append(errs, fmt.Errorf("%s: model does not support images", names[i]))
+			continue
+		}
+		// 开启工具时备用服务需支持工具调用，避免故障转移后丢失工具
+		if i > 0 && len(req.Tools) > 0 && !supportsTools(client) {
+			errs = append(errs, fmt.Errorf("%s: client does not support tools", names[i]))
+			continue
+		}
+		started, err := streamChatTracked(ctx, names[i], client, req)
+		if err == nil {
+			req.ServedBy = names[i]
+			return nil
+		}
+		// 已有内容输出或请求被取消时不再重试，避免卡片出现重复的半截回答
+		if started || ctx.Err() != nil {
+			req.ServedBy = names[i]
+			return err
+		}
+		hlog.Warnf("client %s failed before streaming, try next: %v", names[i], err)
+		errs = append(errs, fmt.Errorf("%s: %w", names[i], err))
+	}
+	req.Model = model
+	return errors.Join(errs...)
+}
+
+// streamChatNamed 使用指定名称的客户端发送聊天请求
+func (m *Manager) streamChatNamed(ctx context.Context, name string, req *AiChatStreamRequest) error {
+	client, err := m.GetClient(name)
+	if err != nil {
+		return err
+	}
+	req.ServedBy = name
+	return streamChatClient(ctx, name, client, req)
+}
+
+// streamChatClient 调用客户端，客户端不支持工具调用时不发送工具定义
+func streamChatClient(ctx context.Context, name string, client Client, req *AiChatStreamRequest) error {
+	if len(req.Tools) == 0 || supportsTools(client) {
+		return client.StreamChat(ctx, req)
+	}
+	hlog.Warnf("client %s does not support tools, request without tools", name)
+	noTools := *req
+	noTools.Tools = nil
+	err := client.StreamChat(ctx, &noTools)
+	req.Usage = noTools.Usage
+	req.FinishReason = noTools.FinishReason
+	return err
+}
+
+// streamChatTracked 代理请求的事件流，返回客户端是否已经输出过内容
+func streamChatTracked(ctx context.Context, name string, client Client, req *AiChatStreamRequest) (bool, error) {
+	var started atomic.Bool
+	tracked := *req
+	if req.Events != nil {
+		// 客户端只写入内容事件，收到任意事件即视为已输出
+		tracked.Events = make(chan StreamEvent)
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for event := range tracked.Events {
+				started.Store(true)
+				_ = sendStream(ctx, req.Events, event)
+			}
+		}()
+		defer func() {
+			close(tracked.Events)
+			<-done
+		}()
+	}
+
+	err := streamChatClient(ctx, name, client, &tracked)
+	req.ToolCalls = tracked.ToolCalls
+	req.Usage = tracked.Usage
+	req.FinishReason = tracked.FinishReason
+	return started.Load(), err
+}
+
+// modelOrDefault 返回请求指定的模型，未指定时使用默认模型
+func modelOrDefault(req *AiChatStreamRequest, defaultModel string) string {
+	if req.Model != "" {
+		return req.Model
+	}
+	return defaultModel
+}
+
+// firstModel 返回客户端的默认模型
+func firstModel(client Client) string {
+	if models := client.GetModels(); len(models) > 0 {
+		return models[0]
+	}
+	return ""
+}
+
+// configuredModels 合并默认模型与可选模型列表，默认模型排在首位并去重
+func configuredModels(defaultModel string, models []string) []string {
+	result := make([]string, 0, len(models)+1)
+	if defaultModel != "" {
+		result = append(result, defaultModel)
+	}
+	for _, m := range models {
+		if m != "" && !slices.Contains(result, m) {
+			result = append(result, m)
+		}
+	}
+	return result
 }
 
 // Factory 定义 AI 客户端工厂接口
 type Factory interface {
-	// CreateClient 创建 AI 客户端
-	CreateClient(provider Provider) (Client, error)
+	// CreateClient 根据实例配置创建 AI 客户端
+	CreateClient(cfg *config.InstanceConfig) (Client, error)
 }
@@ -0,0 +1,164 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordingClient 记录收到的模型和工具数量，fail 为 true 时在输出前返回错误，failAfterOutput 为 true 时在输出后返回错误
+type recordingClient struct {
+	models          []string
+	fail            bool
+	failAfterOutput bool
+	tools           bool
+	got             []string
+	gotTools        []int
+}
+
+func (c *recordingClient) GetProvider() Provider { return ProviderOpenAI }
+func (c *recordingClient) GetModels() []string   { return c.models }
+func (c *recordingClient) SupportsTools() bool   { return c.tools }
+
+func (c *recordingClient) StreamChat(ctx context.Context, req *AiChatStreamRequest) error {
+	c.got = append(c.got, req.Model)
+	c.gotTools = append(c.gotTools, len(req.Tools))
+	if c.fail {
+		return errors.New("unavailable")
+	}
+	if err := req.emitAnswer(ctx, "ok"); err != nil || !c.failAfterOutput {
+		return err
+	}
+	return errors.New("connection reset")
+}
+
+func TestStreamChatFallbackModel(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		backup     []string
+		wantBackup []string
+		wantModel  string
+	}{
+		{name: "default model", model: "", backup: []string{"b-1"}, wantBackup: []string{""}, wantModel: ""},
+		{name: "backup uses its default for a foreign model", model: "a-2", backup: []string{"b-1"}, wantBackup: []string{"b-1"}, wantModel: "b-1"},
+		{name: "backup keeps a model it provides", model: "shared", backup: []string{"b-1", "shared"}, wantBackup: []string{"shared"}, wantModel: "shared"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &recordingClient{models: []string{"a-1", "a-2", "shared"}, fail: true}
+			backup := &recordingClient{models: tt.backup}
+			m := &Manager{clients: map[string]Client{"a": primary, "b": backup}}
+			if err := m.SetFallbackChain("a", "b"); err != nil {
+				t.Fatal(err)
+			}
+			req := &AiChatStreamRequest{Model: tt.model}
+			for event := range m.StreamEvents(context.Background(), "", req) {
+				if event.Kind == EventError {
+					t.Fatalf("StreamEvents() error = %v", event.Err)
+				}
+			}
+			if !reflect.DeepEqual(primary.got, []string{tt.model}) {
+				t.Errorf("primary got %q, want %q", primary.got, tt.model)
+			}
+			if !reflect.DeepEqual(backup.got, tt.wantBackup) {
+				t.Errorf("backup got %q, want %q", backup.got, tt.wantBackup)
+			}
+			if req.ServedBy != "b" || req.Model != tt.wantModel {
+				t.Errorf("served by %s with %q, want b with %q", req.ServedBy, req.Model, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestStreamChatFallbackAfterOutput(t *testing.T) {
+	primary := &recordingClient{failAfterOutput: true}
+	backup := &recordingClient{}
+	m := &Manager{clients: map[string]Client{"a": primary, "b": backup}}
+	if err := m.SetFallbackChain("a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	var answer string
+	var streamErr error
+	for event := range m.StreamEvents(context.Background(), "", &AiChatStreamRequest{}) {
+		switch event.Kind {
+		case EventAnswerDelta:
+			answer += event.Text
+		case EventError:
+			streamErr = event.Err
+		}
+	}
+	if streamErr == nil || !strings.Contains(streamErr.Error(), "connection reset") {
+		t.Errorf("StreamEvents() error = %v, want primary error", streamErr)
+	}
+	if answer != "ok" {
+		t.Errorf("answer = %q, want primary output only", answer)
+	}
+	if len(primary.got) != 1 || len(backup.got) != 0 {
+		t.Errorf("primary called %d times, backup called %d times, want 1 and 0", len(primary.got), len(backup.got))
+	}
+}
+
+func TestStreamChatTools(t *testing.T) {
+	tools := []ToolDefinition{{Name: "calc"}}
+	tests := []struct {
+		name         string
+		chain        []string
+		clients      map[string]*recordingClient
+		wantServedBy string
+		wantTools    map[string][]int
+	}{
+		{
+			name:         "client without tool support gets no tools",
+			chain:        []string{"a"},
+			clients:      map[string]*recordingClient{"a": {}},
+			wantServedBy: "a",
+			wantTools:    map[string][]int{"a": {0}},
+		},
+		{
+			name:         "client with tool support gets tools",
+			chain:        []string{"a"},
+			clients:      map[string]*recordingClient{"a": {tools: true}},
+			wantServedBy: "a",
+			wantTools:    map[string][]int{"a": {1}},
+		},
+		{
+			name:  "fallback skips backup without tool support",
+			chain: []string{"a", "b", "c"},
+			clients: map[string]*recordingClient{
+				"a": {tools: true, fail: true},
+				"b": {},
+				"c": {tools: true},
+			},
+			wantServedBy: "c",
+			wantTools:    map[string][]int{"a": {1}, "b": nil, "c": {1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &Manager{clients: map[string]Client{}}
+			for name, client := range tt.clients {
+				m.clients[name] = client
+			}
+			if err := m.SetFallbackChain(tt.chain...); err != nil {
+				t.Fatal(err)
+			}
+			req := &AiChatStreamRequest{Tools: tools}
+			for event := range m.StreamEvents(context.Background(), "", req) {
+				if event.Kind == EventError {
+					t.Fatalf("StreamEvents() error = %v", event.Err)
+				}
+			}
+			if req.ServedBy != tt.wantServedBy {
+				t.Errorf("served by %s, want %s", req.ServedBy, tt.wantServedBy)
+			}
+			for name, want := range tt.wantTools {
+				if got := tt.clients[name].gotTools; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s got tools %v, want %v", name, got, want)
+				}
+			}
+		})
+	}
+}
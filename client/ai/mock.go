@@ -0,0 +1,194 @@
+package ai
+
+import (
+	"ai-stream-bot/config"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	mockDefaultModel      = "mock"
+	mockDefaultChunkSize  = 4
+	mockDefaultChunkDelay = 50 * time.Millisecond
+)
+
+// MockClient 按脚本文件返回固定内容的模拟服务，无需模型服务的凭证和网络，用于本地开发和演示
+// 每次请求都会重新读取脚本文件，修改后无需重启
+type MockClient struct {
+	cfg *config.MockConfig
+}
+
+// mockFixture 脚本文件，YAML 或 JSON 格式
+type mockFixture struct {
+	Scripts []mockScript `yaml:"scripts"`
+}
+
+// mockScript 一次回答的脚本，按顺序匹配提问，命中第一条即停止
+type mockScript struct {
+	// Match 最后一条用户消息包含该内容时使用，为空时匹配所有提问
+	Match string `yaml:"match"`
+	// ChunkSize 思考和回答按字符切分的片段长度，默认 4
+	ChunkSize int `yaml:"chunk_size"`
+	// ChunkDelay 片段之间的间隔，默认 50ms
+	ChunkDelay time.Duration `yaml:"chunk_delay"`
+	// FinishReason 结束原因，默认 stop，设置为 length 可展示继续生成按钮
+	FinishReason string        `yaml:"finish_reason"`
+	Segments     []mockSegment `yaml:"segments"`
+}
+
+// mockSegment 脚本中的一段输出，每段只设置一种内容
+type mockSegment struct {
+	Think     string         `yaml:"think"`
+	Answer    string         `yaml:"answer"`
+	Reference *mockReference `yaml:"reference"`
+	// Stall 停顿指定时长后继续，首段停顿超过 10s 可触发无内容超时
+	Stall time.Duration `yaml:"stall"`
+	// Error 返回错误并结束，按状态码和错误信息归类
+	Error *mockError `yaml:"error"`
+}
+
+type mockReference struct {
+	Title   string `yaml:"title"`
+	URL     string `yaml:"url"`
+	Site    string `yaml:"site"`
+	Snippet string `yaml:"snippet"`
+	Cover   string `yaml:"cover"`
+}
+
+type mockError struct {
+	Status  int    `yaml:"status"`
+	Code    string `yaml:"code"`
+	Message string `yaml:"message"`
+}
+
+func NewMockClient(cfg *config.MockConfig) *MockClient {
+	return &MockClient{cfg: cfg}
+}
+
+func (c *MockClient) GetProvider() Provider {
+	return ProviderMock
+}
+
+func (c *MockClient) GetModels() []string {
+	defaultModel := c.cfg.Model
+	if defaultModel == "" {
+		defaultModel = mockDefaultModel
+	}
+	return configuredModels(defaultModel, c.cfg.Models)
+}
+
+func (c *MockClient) SupportsVision(model string) bool {
+	return slices.Contains(c.cfg.VisionModels, model)
+}
+
+// StreamChat 按匹配到的脚本逐段输出，用量按字符数估算
+func (c *MockClient) StreamChat(ctx context.Context, req *AiChatStreamRequest) error {
+	question := ""
+	for i := len(req.Msgs) - 1; i >= 0; i-- {
+		if req.Msgs[i].Role == "user" {
+			question = req.Msgs[i].Content
+			break
+		}
+	}
+	script, err := c.matchScript(question)
+	if err != nil {
+		return err
+	}
+	chunkSize, chunkDelay := script.ChunkSize, script.ChunkDelay
+	if chunkSize <= 0 {
+		chunkSize = mockDefaultChunkSize
+	}
+	if chunkDelay <= 0 {
+		chunkDelay = mockDefaultChunkDelay
+	}
+
+	usage := &Usage{}
+	for _, m := range req.Msgs {
+		usage.PromptTokens += len([]rune(m.Content))
+	}
+	for _, segment := range script.Segments {
+		switch {
+		case segment.Error != nil:
+			return newAPIError(string(ProviderMock), segment.Error.Status, segment.Error.Code, segment.Error.Message)
+		case segment.Stall > 0:
+			err = sleepContext(ctx, segment.Stall)
+		case segment.Reference != nil:
+			ref := segment.Reference
+			err = req.emitReference(ctx, Reference{Title: ref.Title, URL: ref.URL, Site: ref.Site, Snippet: ref.Snippet, Cover: ref.Cover})
+		case segment.Think != "":
+			usage.ReasoningTokens += len([]rune(segment.Think))
+			err = streamMockChunks(ctx, segment.Think, chunkSize, chunkDelay, req.emitThink)
+		default:
+			err = streamMockChunks(ctx, segment.Answer, chunkSize, chunkDelay, req.emitAnswer)
+			usage.CompletionTokens += len([]rune(segment.Answer))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// 与 OpenAI 一致，输出 token 中包含思考 token
+	usage.CompletionTokens += usage.ReasoningTokens
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	req.Usage = usage
+	req.FinishReason = script.FinishReason
+	if req.FinishReason == "" {
+		req.FinishReason = "stop"
+	}
+	return nil
+}
+
+// matchScript 读取脚本文件并返回第一条匹配的脚本，未配置脚本文件时复述提问
+func (c *MockClient) matchScript(question string) (*mockScript, error) {
+	if c.cfg.Fixture == "" {
+		return &mockScript{Segments: []mockSegment{
+			{Think: "这是模拟服务的思考内容。"},
+			{Answer: "收到你的问题：" + question},
+		}}, nil
+	}
+	data, err := os.ReadFile(c.cfg.Fixture)
+	if err != nil {
+		return nil, fmt.Errorf("read mock fixture error: %w", err)
+	}
+	fixture := mockFixture{}
+	if err := yaml.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("parse mock fixture %s error: %w", c.cfg.Fixture, err)
+	}
+	for i := range fixture.Scripts {
+		if strings.Contains(question, fixture.Scripts[i].Match) {
+			return &fixture.Scripts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("mock fixture %s has no script matching the question", c.cfg.Fixture)
+}
+
+// streamMockChunks 按字符切分内容，每个片段间隔 delay 写入
+func streamMockChunks(ctx context.Context, text string, size int, delay time.Duration, emit func(context.Context, string) error) error {
+	runes := []rune(text)
+	for start := 0; start < len(runes); start += size {
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+		if err := emit(ctx, string(runes[start:min(start+size, len(runes))])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sleepContext 等待指定时长，ctx 取消时立即返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
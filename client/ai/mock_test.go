@@ -0,0 +1,163 @@
+package ai
+
+import (
+	"ai-stream-bot/config"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMockFixture = `
+scripts:
+  - match: "引用"
+    chunk_delay: 1ms
+    segments:
+      - think: "先想一想"
+      - reference:
+          title: 文档
+          url: https://example.com/doc
+      - answer: "见文档[1]"
+  - match: "截断"
+    chunk_size: 2
+    chunk_delay: 1ms
+    finish_reason: length
+    segments:
+      - answer: "被截断的回答"
+  - match: "限流"
+    chunk_delay: 1ms
+    segments:
+      - answer: "部分内容"
+      - error:
+          status: 429
+          code: rate_limit_exceeded
+          message: Rate limit reached
+  - match: ""
+    chunk_delay: 1ms
+    segments:
+      - answer: "兜底"
+`
+
+func writeFixture(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMockMatchScript(t *testing.T) {
+	yamlFixture := writeFixture(t, "fixture.yaml", testMockFixture)
+	jsonFixture := writeFixture(t, "fixture.json", `{"scripts": [{"match": "你好", "chunk_size": 3, "chunk_delay": "20ms", "segments": [{"answer": "你好呀"}]}]}`)
+	invalidFixture := writeFixture(t, "invalid.yaml", "scripts: [")
+	tests := []struct {
+		name       string
+		fixture    string
+		question   string
+		wantAnswer string
+		wantSize   int
+		wantDelay  time.Duration
+		wantErr    string
+	}{
+		{name: "first match wins", fixture: yamlFixture, question: "带引用的截断回答", wantAnswer: "见文档[1]", wantDelay: time.Millisecond},
+		{name: "finish reason script", fixture: yamlFixture, question: "截断", wantAnswer: "被截断的回答", wantSize: 2, wantDelay: time.Millisecond},
+		{name: "empty match as fallback", fixture: yamlFixture, question: "随便问问", wantAnswer: "兜底", wantDelay: time.Millisecond},
+		{name: "json fixture", fixture: jsonFixture, question: "你好", wantAnswer: "你好呀", wantSize: 3, wantDelay: 20 * time.Millisecond},
+		{name: "no matching script", fixture: jsonFixture, question: "再见", wantErr: "no script matching"},
+		{name: "invalid fixture", fixture: invalidFixture, question: "你好", wantErr: "parse mock fixture"},
+		{name: "missing fixture", fixture: filepath.Join(t.TempDir(), "missing.yaml"), question: "你好", wantErr: "read mock fixture"},
+		{name: "echo without fixture", question: "你好", wantAnswer: "收到你的问题：你好"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewMockClient(&config.MockConfig{Fixture: tt.fixture})
+			script, err := client.matchScript(tt.question)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("matchScript() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("matchScript() error = %v", err)
+			}
+			last := script.Segments[len(script.Segments)-1]
+			if last.Answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", last.Answer, tt.wantAnswer)
+			}
+			if script.ChunkSize != tt.wantSize || script.ChunkDelay != tt.wantDelay {
+				t.Errorf("chunk = %d/%v, want %d/%v", script.ChunkSize, script.ChunkDelay, tt.wantSize, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestMockFixtureExample(t *testing.T) {
+	client := NewMockClient(&config.MockConfig{Fixture: "../../mock_fixture_example.yaml"})
+	if _, err := client.matchScript("任意问题"); err != nil {
+		t.Fatalf("example fixture error = %v", err)
+	}
+}
+
+func TestMockStreamChat(t *testing.T) {
+	fixture := writeFixture(t, "fixture.yaml", testMockFixture)
+	tests := []struct {
+		name       string
+		question   string
+		wantThink  string
+		wantAnswer string
+		wantRefs   []string
+		wantFinish string
+		wantErr    error
+	}{
+		{name: "think reference answer", question: "引用", wantThink: "先想一想", wantAnswer: "见文档[1]", wantRefs: []string{"https://example.com/doc"}, wantFinish: "stop"},
+		{name: "finish reason", question: "截断", wantAnswer: "被截断的回答", wantFinish: FinishReasonLength},
+		{name: "error after partial answer", question: "限流", wantAnswer: "部分内容", wantErr: ErrRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &AiChatStreamRequest{Msgs: []AiMessage{{Role: "user", Content: tt.question}}, Events: make(chan StreamEvent)}
+			var think, answer string
+			var refs []string
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for event := range req.Events {
+					switch event.Kind {
+					case EventThinkDelta:
+						think += event.Text
+					case EventAnswerDelta:
+						answer += event.Text
+					case EventReference:
+						refs = append(refs, event.Reference.URL)
+					}
+				}
+			}()
+			err := NewMockClient(&config.MockConfig{Fixture: fixture}).StreamChat(context.Background(), req)
+			close(req.Events)
+			<-done
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("StreamChat() error = %v, want %v", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("StreamChat() error = %v", err)
+			}
+			if think != tt.wantThink || answer != tt.wantAnswer {
+				t.Errorf("think = %q, answer = %q, want %q, %q", think, answer, tt.wantThink, tt.wantAnswer)
+			}
+			if !reflect.DeepEqual(refs, tt.wantRefs) {
+				t.Errorf("references = %v, want %v", refs, tt.wantRefs)
+			}
+			if req.FinishReason != tt.wantFinish {
+				t.Errorf("FinishReason = %q, want %q", req.FinishReason, tt.wantFinish)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package ai
+
+import (
+	"ai-stream-bot/config"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const ollamaDefaultAPIURL = "http://localhost:11434"
+
+type OllamaClient struct {
+	cfg        *config.OllamaConfig
+	httpClient *http.Client
+}
+
+// ollamaChatRequest /api/chat 请求体
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Options  *ollamaOptions  `json:"options,omitempty"`
+}
+
+// ollamaOptions 生成参数，未配置的参数使用模型的 Modelfile 设置
+type ollamaOptions struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	NumPredict      int      `json:"num_predict,omitempty"`
+	Stop            []string `json:"stop,omitempty"`
+	PresencePenalty *float64 `json:"presence_penalty,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+}
+
+// ollamaMessage /api/chat 消息，图片以 base64 单独传递
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"`
+}
+
+// ollamaChatChunk /api/chat 流式返回的单行 JSON
+type ollamaChatChunk struct {
+	Message struct {
+		Role     string `json:"role"`
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	Error           string `json:"error"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+}
+
+func NewOllamaClient(cfg *config.OllamaConfig) *OllamaClient {
+	return &OllamaClient{cfg: cfg, httpClient: http.DefaultClient}
+}
+
+func (c *OllamaClient) GetProvider() Provider {
+	return ProviderOllama
+}
+
+func (c *OllamaClient) GetModels() []string {
+	return configuredModels(c.cfg.Model, c.cfg.Models)
+}
+
+func (c *OllamaClient) SupportsVision(model string) bool {
+	return slices.Contains(c.cfg.VisionModels, model)
+}
+
+func (c *OllamaClient) GetGeneration() config.GenerationConfig {
+	return withGenerationDefaults(c.cfg.Generation)
+}
+
+func (c *OllamaClient) StreamChat(ctx context.Context, req *AiChatStreamRequest) error {
+	// reasoning_effort 暂不支持
+	params := c.GetGeneration().Merge(req.Params)
+	body, err := json.Marshal(ollamaChatRequest{
+		Model:    modelOrDefault(req, c.cfg.Model),
+		Messages: ollamaMessages(req.Msgs),
+		Stream:   true,
+		Options: &ollamaOptions{
+			Temperature:     params.Temperature,
+			TopP:            params.TopP,
+			NumPredict:      params.MaxTokens,
+			Stop:            params.Stop,
+			PresencePenalty: params.PresencePenalty,
+			Seed:            params.Seed,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.chatURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		hlog.Errorf("ollama chat request error: %v", err)
+		return wrapRequestError(ctx, string(ProviderOllama), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		chunk := ollamaChatChunk{}
+		if err := json.Unmarshal(raw, &chunk); err == nil && chunk.Error != "" {
+			return newAPIError(string(ProviderOllama), resp.StatusCode, "", chunk.Error)
+		}
+		return newAPIError(string(ProviderOllama), resp.StatusCode, "", strings.TrimSpace(string(raw)))
+	}
+
+	demux := newThinkDemux(req)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		chunk := ollamaChatChunk{}
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			hlog.Errorf("unmarshal ollama chunk error: %v, data: %s", err, line)
+			return err
+		}
+		if chunk.Error != "" {
+			return newAPIError(string(ProviderOllama), 0, "", chunk.Error)
+		}
+		// 新版本 ollama 开启 think 后会单独返回 thinking 字段
+		if err := req.emitThink(ctx, chunk.Message.Thinking); err != nil {
+			return err
+		}
+		if err := demux.Write(ctx, chunk.Message.Content); err != nil {
+			return err
+		}
+		if chunk.Done {
+			req.FinishReason = chunk.DoneReason
+			req.Usage = &Usage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+				TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+			}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		hlog.Errorf("ollama stream error: %v", err)
+		return wrapRequestError(ctx, string(ProviderOllama), err)
+	}
+	return demux.Flush(ctx)
+}
+
+// Embed 调用 /api/embed 接口，需配置 embedding_model
+func (c *OllamaClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if c.cfg.EmbeddingModel == "" {
+		return nil, fmt.Errorf("ollama embedding_model not configured")
+	}
+	body, err := json.Marshal(map[string]any{
+		"model": c.cfg.EmbeddingModel,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimSuffix(strings.TrimRight(c.cfg.APIURL, "/"), "/api/chat")
+	if baseURL == "" {
+		baseURL = ollamaDefaultAPIURL
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		hlog.Errorf("ollama embed request error: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+		Error      string      `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("ollama embed error: status %d, %w", resp.StatusCode, err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("ollama embed error: status %d, message: %s", resp.StatusCode, result.Error)
+	}
+	return checkEmbeddings(result.Embeddings, texts)
+}
+
+// chatURL 拼接 /api/chat 地址，api_url 形如 http://localhost:11434
+func (c *OllamaClient) chatURL() string {
+	baseURL := strings.TrimRight(c.cfg.APIURL, "/")
+	if baseURL == "" {
+		baseURL = ollamaDefaultAPIURL
+	}
+	if strings.HasSuffix(baseURL, "/api/chat") {
+		return baseURL
+	}
+	return baseURL + "/api/chat"
+}
+
+// ollamaMessages 转换消息，只支持内联图片
+func ollamaMessages(msgs []AiMessage) []ollamaMessage {
+	result := make([]ollamaMessage, len(msgs))
+	for i, m := range msgs {
+		result[i] = ollamaMessage{Role: m.Role, Content: m.Content}
+		for _, image := range m.Images() {
+			if _, data, ok := image.ImageData(); ok {
+				result[i].Images = append(result[i].Images, data)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,155 @@
+package ai
+
+import (
+	"ai-stream-bot/config"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestOllamaClient(server *httptest.Server) *OllamaClient {
+	client := NewOllamaClient(&config.OllamaConfig{Model: "qwen-test", APIURL: server.URL})
+	client.httpClient = server.Client()
+	return client
+}
+
+// ollamaLines 拼接 NDJSON，每行一个 JSON
+func ollamaLines(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestOllamaStreamChat(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		wantThink        string
+		wantAnswer       string
+		wantFinishReason string
+		wantUsage        *Usage
+	}{
+		{
+			name: "think tags split across lines",
+			body: ollamaLines(
+				`{"message":{"role":"assistant","content":"<thi"},"done":false}`,
+				`{"message":{"role":"assistant","content":"nk>想一"},"done":false}`,
+				`{"message":{"role":"assistant","content":"想</th"},"done":false}`,
+				`{"message":{"role":"assistant","content":"ink>你好"},"done":false}`,
+				`{"message":{"role":"assistant","content":"，世界"},"done":false}`,
+				`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"stop","prompt_eval_count":12,"eval_count":7}`,
+			),
+			wantThink:        "想一想",
+			wantAnswer:       "你好，世界",
+			wantFinishReason: "stop",
+			wantUsage:        &Usage{PromptTokens: 12, CompletionTokens: 7, TotalTokens: 19},
+		},
+		{
+			name: "thinking field and blank lines",
+			body: ollamaLines(
+				`{"message":{"role":"assistant","content":"","thinking":"想"},"done":false}`,
+				``,
+				`{"message":{"role":"assistant","content":"a"},"done":false}`,
+				`{"message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":4}`,
+			),
+			wantThink:        "想",
+			wantAnswer:       "a",
+			wantFinishReason: FinishReasonLength,
+			wantUsage:        &Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7},
+		},
+		{
+			name: "lines after done are ignored",
+			body: ollamaLines(
+				`{"message":{"role":"assistant","content":"a"},"done":true,"done_reason":"stop"}`,
+				`{"message":{"role":"assistant","content":"b"},"done":false}`,
+			),
+			wantAnswer:       "a",
+			wantFinishReason: "stop",
+			wantUsage:        &Usage{},
+		},
+		{
+			name:       "unclosed think tag is flushed",
+			body:       ollamaLines(`{"message":{"role":"assistant","content":"<think>想"},"done":false}`),
+			wantThink:  "想",
+			wantAnswer: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStreamServer(t, "/api/chat", "application/x-ndjson", http.StatusOK, tt.body, nil)
+			req := &AiChatStreamRequest{Msgs: []AiMessage{{Role: "user", Content: "hi"}}}
+			result := runStreamChat(newTestOllamaClient(server), req)
+			if result.err != nil {
+				t.Fatalf("StreamChat() error = %v", result.err)
+			}
+			if result.think != tt.wantThink {
+				t.Errorf("think = %q, want %q", result.think, tt.wantThink)
+			}
+			if result.answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", result.answer, tt.wantAnswer)
+			}
+			if req.FinishReason != tt.wantFinishReason {
+				t.Errorf("FinishReason = %q, want %q", req.FinishReason, tt.wantFinishReason)
+			}
+			if !reflect.DeepEqual(req.Usage, tt.wantUsage) {
+				t.Errorf("Usage = %+v, want %+v", req.Usage, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestOllamaStreamChatError(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantKind   error
+		wantStatus int
+		wantAnswer string
+	}{
+		{
+			name:   "error line in stream",
+			status: http.StatusOK,
+			body: ollamaLines(
+				`{"message":{"role":"assistant","content":"a"},"done":false}`,
+				`{"error":"an error was encountered while running the model: context length exceeded"}`,
+			),
+			wantKind:   ErrContextTooLong,
+			wantAnswer: "a",
+		},
+		{
+			name:       "error response",
+			status:     http.StatusInternalServerError,
+			body:       `{"error":"llama runner process has terminated"}`,
+			wantKind:   ErrUpstream5xx,
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "plain text error response",
+			status:     http.StatusBadGateway,
+			body:       "bad gateway\n",
+			wantKind:   ErrUpstream5xx,
+			wantStatus: http.StatusBadGateway,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newStreamServer(t, "/api/chat", "application/x-ndjson", tt.status, tt.body, nil)
+			result := runStreamChat(newTestOllamaClient(server), &AiChatStreamRequest{})
+			if !errors.Is(result.err, tt.wantKind) {
+				t.Fatalf("StreamChat() error = %v, want %v", result.err, tt.wantKind)
+			}
+			var apiErr *Error
+			if !errors.As(result.err, &apiErr) {
+				t.Fatalf("StreamChat() error = %T, want *Error", result.err)
+			}
+			if apiErr.StatusCode != tt.wantStatus || apiErr.Message == "" {
+				t.Errorf("error = %+v", apiErr)
+			}
+			if result.answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", result.answer, tt.wantAnswer)
+			}
+		})
+	}
+}
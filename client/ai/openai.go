@@ -2,22 +2,330 @@ package ai
 
 import (
 	"ai-stream-bot/config"
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
 )
 
 type OpenAIClient struct {
-	cfg *config.OpenAIConfig
+	cfg        *config.OpenAIConfig
+	httpClient *http.Client
+}
+
+// openAIChatRequest Chat Completions 请求体
+type openAIChatRequest struct {
+	Model     string       `json:"model"`
+	Messages  []AiMessage  `json:"messages"`
+	Stream    bool         `json:"stream"`
+	MaxTokens int          `json:"max_tokens,omitempty"`
+	Tools     []openAITool `json:"tools,omitempty"`
+	// 未配置的生成参数不发送
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"top_p,omitempty"`
+	Stop            []string `json:"stop,omitempty"`
+	PresencePenalty *float64 `json:"presence_penalty,omitempty"`
+	Seed            *int     `json:"seed,omitempty"`
+	ReasoningEffort string   `json:"reasoning_effort,omitempty"`
+	// StreamOptions 开启 include_usage 后最后一个 chunk 会返回用量
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIUsage Chat Completions 用量
+type openAIUsage struct {
+	PromptTokens            int `json:"prompt_tokens"`
+	CompletionTokens        int `json:"completion_tokens"`
+	TotalTokens             int `json:"total_tokens"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+// openAITool Chat Completions 工具定义
+type openAITool struct {
+	Type     string         `json:"type"`
+	Function ToolDefinition `json:"function"`
+}
+
+// openAIChatChunk Chat Completions 流式返回的单个 chunk
+type openAIChatChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content          string `json:"content"`
+			ReasoningContent string `json:"reasoning_content"`
+			ToolCalls        []struct {
+				Index    int              `json:"index"`
+				ID       string           `json:"id"`
+				Type     string           `json:"type"`
+				Function ToolCallFunction `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// openAIErrorResponse OpenAI 兼容接口的错误返回
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    any    `json:"code"`
+	} `json:"error"`
 }
 
 func NewOpenAIClient(cfg *config.OpenAIConfig) *OpenAIClient {
-	return &OpenAIClient{cfg: cfg}
+	return &OpenAIClient{cfg: cfg, httpClient: http.DefaultClient}
+}
+
+// NewOpenAIClientWithHTTPClient 使用自定义 http.Client 创建客户端，便于接入代理或测试
+func NewOpenAIClientWithHTTPClient(cfg *config.OpenAIConfig, httpClient *http.Client) *OpenAIClient {
+	return &OpenAIClient{cfg: cfg, httpClient: httpClient}
+}
+
+func (c *OpenAIClient) GetGeneration() config.GenerationConfig {
+	return withGenerationDefaults(c.cfg.Generation)
 }
 
 func (c *OpenAIClient) StreamChat(ctx context.Context, req *AiChatStreamRequest) error {
-	// to be implemented
-	return nil
+	params := c.GetGeneration().Merge(req.Params)
+	body, err := json.Marshal(openAIChatRequest{
+		Model:           modelOrDefault(req, c.cfg.Model),
+		Messages:        req.Msgs,
+		Stream:          true,
+		MaxTokens:       params.MaxTokens,
+		Tools:           buildOpenAITools(req.Tools),
+		StreamOptions:   &openAIStreamOptions{IncludeUsage: true},
+		Temperature:     params.Temperature,
+		TopP:            params.TopP,
+		Stop:            params.Stop,
+		PresencePenalty: params.PresencePenalty,
+		Seed:            params.Seed,
+		ReasoningEffort: params.ReasoningEffort,
+	})
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.chatCompletionsURL(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if c.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		hlog.Errorf("openai chat completions request error: %v", err)
+		return wrapRequestError(ctx, string(ProviderOpenAI), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return parseOpenAIError(resp)
+	}
+
+	// 工具调用的参数分多个 chunk 返回，按 index 拼接
+	var toolCalls []ToolCall
+	// 部分网关将思考内容以 <think> 标签放在 content 中
+	demux := newThinkDemux(req)
+	defer func() {
+		req.ToolCalls = toolCalls
+	}()
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// SSE 中空行为事件分隔，冒号开头为注释（部分网关用于保活）
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		data, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return demux.Flush(ctx)
+		}
+
+		chunk := openAIChatChunk{}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			hlog.Errorf("unmarshal openai chunk error: %v, data: %s", err, data)
+			return err
+		}
+		if chunk.Usage != nil {
+			req.Usage = &Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				ReasoningTokens:  chunk.Usage.CompletionTokensDetails.ReasoningTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			}
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+		if reason := chunk.Choices[0].FinishReason; reason != nil && *reason != "" {
+			req.FinishReason = *reason
+		}
+		delta := chunk.Choices[0].Delta
+		for _, call := range delta.ToolCalls {
+			for len(toolCalls) <= call.Index {
+				toolCalls = append(toolCalls, ToolCall{Type: "function"})
+			}
+			if call.ID != "" {
+				toolCalls[call.Index].ID = call.ID
+			}
+			toolCalls[call.Index].Function.Name += call.Function.Name
+			toolCalls[call.Index].Function.Arguments += call.Function.Arguments
+		}
+		if err := req.emitThink(ctx, delta.ReasoningContent); err != nil {
+			return err
+		}
+		if err := demux.Write(ctx, delta.Content); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		hlog.Errorf("openai stream error: %v", err)
+		return wrapRequestError(ctx, string(ProviderOpenAI), err)
+	}
+	return demux.Flush(ctx)
 }
 
 func (c *OpenAIClient) GetProvider() Provider {
 	return ProviderOpenAI
 }
+
+func (c *OpenAIClient) GetModels() []string {
+	return configuredModels(c.cfg.Model, c.cfg.Models)
+}
+
+func (c *OpenAIClient) SupportsVision(model string) bool {
+	return slices.Contains(c.cfg.VisionModels, model)
+}
+
+func (c *OpenAIClient) SupportsTools() bool {
+	return true
+}
+
+// chatCompletionsURL 拼接 Chat Completions 地址，api_url 形如 https://api.openai.com/v1
+func (c *OpenAIClient) chatCompletionsURL() string {
+	baseURL := strings.TrimRight(c.cfg.APIURL, "/")
+	if strings.HasSuffix(baseURL, "/chat/completions") {
+		return baseURL
+	}
+	return baseURL + "/chat/completions"
+}
+
+// Embed 调用 Embeddings 接口，需配置 embedding_model
+func (c *OpenAIClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if c.cfg.EmbeddingModel == "" {
+		return nil, fmt.Errorf("openai embedding_model not configured")
+	}
+	body, err := json.Marshal(map[string]any{
+		"model": c.cfg.EmbeddingModel,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.embeddingsURL(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.cfg.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		hlog.Errorf("openai embeddings request error: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseOpenAIError(resp)
+	}
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(result.Data))
+	for _, d := range result.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("openai embeddings index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return checkEmbeddings(vectors, texts)
+}
+
+// embeddingsURL 拼接 Embeddings 地址，与 Chat Completions 共用 api_url
+func (c *OpenAIClient) embeddingsURL() string {
+	baseURL := strings.TrimSuffix(strings.TrimRight(c.cfg.APIURL, "/"), "/chat/completions")
+	return baseURL + "/embeddings"
+}
+
+// buildOpenAITools 转换为 Chat Completions 的工具定义
+func buildOpenAITools(defs []ToolDefinition) []openAITool {
+	if len(defs) == 0 {
+		return nil
+	}
+	tools := make([]openAITool, len(defs))
+	for i, def := range defs {
+		tools[i] = openAITool{Type: "function", Function: def}
+	}
+	return tools
+}
+
+// parseOpenAIError 解析非 200 返回的错误信息
+func parseOpenAIError(resp *http.Response) error {
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	errResp := openAIErrorResponse{}
+	if err := json.Unmarshal(raw, &errResp); err == nil && errResp.Error.Message != "" {
+		code := errResp.Error.Type
+		if errResp.Error.Code != nil {
+			code = strings.TrimSpace(fmt.Sprintf("%s %v", code, errResp.Error.Code))
+		}
+		return newAPIError(string(ProviderOpenAI), resp.StatusCode, code, errResp.Error.Message)
+	}
+	return newAPIError(string(ProviderOpenAI), resp.StatusCode, "", strings.TrimSpace(string(raw)))
+}
+
+// sendStream 向流中写入内容，ctx 取消时立即返回，避免消费方退出后阻塞
+func sendStream[T any](ctx context.Context, stream chan T, content T) error {
+	if stream == nil {
+		return nil
+	}
+	select {
+	case stream <- content:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,206 @@
+package ai
+
+import (
+	"ai-stream-bot/config"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// streamResult 客户端写入的事件汇总
+type streamResult struct {
+	think  string
+	answer string
+	refs   []Reference
+	err    error
+}
+
+// runStreamChat 调用客户端并收集写入的思考、回答和引用
+func runStreamChat(client Client, req *AiChatStreamRequest) streamResult {
+	req.Events = make(chan StreamEvent)
+	done := make(chan streamResult)
+	go func() {
+		result := streamResult{}
+		for event := range req.Events {
+			switch event.Kind {
+			case EventThinkDelta:
+				result.think += event.Text
+			case EventAnswerDelta:
+				result.answer += event.Text
+			case EventReference:
+				result.refs = append(result.refs, *event.Reference)
+			}
+		}
+		done <- result
+	}()
+	err := client.StreamChat(context.Background(), req)
+	close(req.Events)
+	result := <-done
+	result.err = err
+	return result
+}
+
+// newSSEServer 按原样返回 body 的 SSE 服务，并记录收到的请求体
+func newSSEServer(t *testing.T, status int, body string, requestBody *string) *httptest.Server {
+	t.Helper()
+	return newStreamServer(t, "/v1/chat/completions", "text/event-stream", status, body, requestBody)
+}
+
+// newStreamServer 校验请求路径后按原样返回 body，并记录收到的请求体
+func newStreamServer(t *testing.T, path, contentType string, status int, body string, requestBody *string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if requestBody != nil {
+			raw, _ := io.ReadAll(r.Body)
+			*requestBody = string(raw)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestOpenAIClient(server *httptest.Server) *OpenAIClient {
+	return NewOpenAIClientWithHTTPClient(&config.OpenAIConfig{
+		APIKey: "sk-test",
+		Model:  "gpt-test",
+		APIURL: server.URL + "/v1",
+	}, server.Client())
+}
+
+func TestOpenAIStreamChat(t *testing.T) {
+	tests := []struct {
+		name             string
+		body             string
+		wantThink        string
+		wantAnswer       string
+		wantFinishReason string
+		wantUsage        *Usage
+	}{
+		{
+			name: "content and reasoning deltas",
+			body: `data: {"choices":[{"delta":{"reasoning_content":"想一"}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"reasoning_content":"想"}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"content":"你好"}}]}` + "\n\n" +
+				`data: {"choices":[{"delta":{"content":"，世界"},"finish_reason":"stop"}]}` + "\n\n" +
+				`data: {"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":5,"total_tokens":8,"completion_tokens_details":{"reasoning_tokens":2}}}` + "\n\n" +
+				"data: [DONE]\n\n",
+			wantThink:        "想一想",
+			wantAnswer:       "你好，世界",
+			wantFinishReason: "stop",
+			wantUsage:        &Usage{PromptTokens: 3, CompletionTokens: 5, ReasoningTokens: 2, TotalTokens: 8},
+		},
+		{
+			name: "keep-alive comments and blank lines are skipped",
+			body: ": keep-alive\n\n\n" +
+				`data: {"choices":[{"delta":{"content":"a"}}]}` + "\n" +
+				": ping\n" +
+				"event: message\n\n" +
+				`data:{"choices":[{"delta":{"content":"b"}}]}` + "\n\n" +
+				"data: [DONE]\n\n",
+			wantAnswer: "ab",
+		},
+		{
+			name: "content after DONE is ignored",
+			body: `data: {"choices":[{"delta":{"content":"a"}}]}` + "\n\n" +
+				"data: [DONE]\n\n" +
+				`data: {"choices":[{"delta":{"content":"b"}}]}` + "\n\n",
+			wantAnswer: "a",
+		},
+		{
+			name:             "stream ends without DONE",
+			body:             `data: {"choices":[{"delta":{"content":"a"},"finish_reason":"length"}]}` + "\n\n",
+			wantAnswer:       "a",
+			wantFinishReason: FinishReasonLength,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSSEServer(t, http.StatusOK, tt.body, nil)
+			req := &AiChatStreamRequest{Msgs: []AiMessage{{Role: "user", Content: "hi"}}}
+			result := runStreamChat(newTestOpenAIClient(server), req)
+			if result.err != nil {
+				t.Fatalf("StreamChat() error = %v", result.err)
+			}
+			if result.think != tt.wantThink {
+				t.Errorf("think = %q, want %q", result.think, tt.wantThink)
+			}
+			if result.answer != tt.wantAnswer {
+				t.Errorf("answer = %q, want %q", result.answer, tt.wantAnswer)
+			}
+			if req.FinishReason != tt.wantFinishReason {
+				t.Errorf("FinishReason = %q, want %q", req.FinishReason, tt.wantFinishReason)
+			}
+			if !reflect.DeepEqual(req.Usage, tt.wantUsage) {
+				t.Errorf("Usage = %+v, want %+v", req.Usage, tt.wantUsage)
+			}
+		})
+	}
+}
+
+func TestOpenAIStreamChatRequest(t *testing.T) {
+	var requestBody string
+	server := newSSEServer(t, http.StatusOK, "data: [DONE]\n\n", &requestBody)
+	req := &AiChatStreamRequest{Msgs: []AiMessage{{Role: "user", Content: "hi"}}, Model: "gpt-other"}
+	if result := runStreamChat(newTestOpenAIClient(server), req); result.err != nil {
+		t.Fatalf("StreamChat() error = %v", result.err)
+	}
+	for _, want := range []string{`"model":"gpt-other"`, `"stream":true`, `"include_usage":true`} {
+		if !strings.Contains(requestBody, want) {
+			t.Errorf("request body %s does not contain %s", requestBody, want)
+		}
+	}
+}
+
+func TestOpenAIStreamChatError(t *testing.T) {
+	body := `{"error":{"message":"Rate limit reached for requests","type":"requests","code":"rate_limit_exceeded"}}`
+	server := newSSEServer(t, http.StatusTooManyRequests, body, nil)
+	result := runStreamChat(newTestOpenAIClient(server), &AiChatStreamRequest{})
+	if !errors.Is(result.err, ErrRateLimited) {
+		t.Fatalf("StreamChat() error = %v, want ErrRateLimited", result.err)
+	}
+	var apiErr *Error
+	if !errors.As(result.err, &apiErr) {
+		t.Fatalf("StreamChat() error = %T, want *Error", result.err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Message != "Rate limit reached for requests" || apiErr.Code != "requests rate_limit_exceeded" {
+		t.Errorf("error = %+v", apiErr)
+	}
+	if result.answer != "" {
+		t.Errorf("answer = %q, want empty", result.answer)
+	}
+}
+
+func TestOpenAIStreamChatToolCalls(t *testing.T) {
+	body := `data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"calc","arguments":""}}]}}]}` + "\n\n" +
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"expr"}}]}}]}` + "\n\n" +
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"current_time","arguments":"{}"}}]}}]}` + "\n\n" +
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\":\"1+1\"}"}}]}}]}` + "\n\n" +
+		`data: {"choices":[{"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n" +
+		"data: [DONE]\n\n"
+	server := newSSEServer(t, http.StatusOK, body, nil)
+	req := &AiChatStreamRequest{}
+	if result := runStreamChat(newTestOpenAIClient(server), req); result.err != nil {
+		t.Fatalf("StreamChat() error = %v", result.err)
+	}
+	want := []ToolCall{
+		{ID: "call_1", Type: "function", Function: ToolCallFunction{Name: "calc", Arguments: `{"expr":"1+1"}`}},
+		{ID: "call_2", Type: "function", Function: ToolCallFunction{Name: "current_time", Arguments: "{}"}},
+	}
+	if !reflect.DeepEqual(req.ToolCalls, want) {
+		t.Errorf("ToolCalls = %+v, want %+v", req.ToolCalls, want)
+	}
+	if req.FinishReason != "tool_calls" {
+		t.Errorf("FinishReason = %q, want tool_calls", req.FinishReason)
+	}
+}
@@ -0,0 +1,18 @@
+package ai
+
+// Reference 回答引用的资料，由客户端写入引用流，同一资料可能重复写入，由使用方按 Key 去重
+type Reference struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Site    string `json:"site"`
+	Snippet string `json:"snippet"`
+	Cover   string `json:"cover"` // 封面图片地址
+}
+
+// Key 去重使用的键，没有地址的资料（如知识库文件）按标题去重
+func (r Reference) Key() string {
+	if r.URL != "" {
+		return r.URL
+	}
+	return r.Title
+}
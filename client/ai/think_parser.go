@@ -0,0 +1,112 @@
+package ai
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// thinkTagParser 将流式文本开头的 <think>…</think> 片段拆分为思考与回答
+// 只识别回答开头（允许前置空白）的标签，回答正文中出现的 <think> 原样保留
+// 标签可能被切分在多个 chunk 中，未能确定归属的尾部内容会暂存到下一次 Feed
+type thinkTagParser struct {
+	state   thinkState
+	pending string
+}
+
+type thinkState int
+
+const (
+	// thinkStateStart 尚未确定开头是否为 <think>
+	thinkStateStart thinkState = iota
+	thinkStateThink
+	thinkStateAnswer
+)
+
+// Feed 输入一段增量文本，返回其中可确定的思考内容与回答内容
+func (p *thinkTagParser) Feed(chunk string) (think string, answer string) {
+	text := p.pending + chunk
+	p.pending = ""
+
+	switch p.state {
+	case thinkStateStart:
+		trimmed := strings.TrimLeft(text, " \t\r\n")
+		switch {
+		case strings.HasPrefix(trimmed, thinkOpenTag):
+			p.state = thinkStateThink
+			return p.Feed(trimmed[len(thinkOpenTag):])
+		case strings.HasPrefix(thinkOpenTag, trimmed):
+			// 只有空白或不完整的开始标签，等待后续内容
+			p.pending = text
+			return "", ""
+		default:
+			p.state = thinkStateAnswer
+			return "", text
+		}
+	case thinkStateThink:
+		if idx := strings.Index(text, thinkCloseTag); idx >= 0 {
+			p.state = thinkStateAnswer
+			return text[:idx], text[idx+len(thinkCloseTag):]
+		}
+		// 末尾可能是不完整的结束标签，暂存等待后续内容
+		keep := partialSuffixLen(text, thinkCloseTag)
+		p.pending = text[len(text)-keep:]
+		return text[:len(text)-keep], ""
+	default:
+		return "", text
+	}
+}
+
+// Flush 流结束时输出暂存的内容，未闭合的 <think> 中的内容作为思考
+func (p *thinkTagParser) Flush() (think string, answer string) {
+	rest := p.pending
+	p.pending = ""
+	if p.state == thinkStateThink {
+		return rest, ""
+	}
+	return "", rest
+}
+
+// thinkDemux 按 <think> 标签将回答增量拆分为思考事件与回答事件，供各客户端共用
+// 部分网关（vLLM、SGLang 等）将推理模型的思考内容以标签形式放在 content 中返回
+type thinkDemux struct {
+	parser thinkTagParser
+	req    *AiChatStreamRequest
+}
+
+func newThinkDemux(req *AiChatStreamRequest) *thinkDemux {
+	return &thinkDemux{req: req}
+}
+
+// Write 拆分一段回答增量并写入对应的事件
+func (d *thinkDemux) Write(ctx context.Context, content string) error {
+	think, answer := d.parser.Feed(content)
+	return d.send(ctx, think, answer)
+}
+
+// Flush 流结束时写入暂存的内容
+func (d *thinkDemux) Flush(ctx context.Context) error {
+	think, answer := d.parser.Flush()
+	return d.send(ctx, think, answer)
+}
+
+func (d *thinkDemux) send(ctx context.Context, think, answer string) error {
+	if err := d.req.emitThink(ctx, think); err != nil {
+		return err
+	}
+	return d.req.emitAnswer(ctx, answer)
+}
+
+// partialSuffixLen 返回 s 的末尾与 tag 前缀重合的最大长度
+func partialSuffixLen(s, tag string) int {
+	for n := min(len(s), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,96 @@
+package ai
+
+import (
+	"context"
+	"testing"
+)
+
+// feedAll 依次输入 chunks 并在结束时 Flush，返回拼接后的思考与回答
+func feedAll(chunks []string) (string, string) {
+	p := thinkTagParser{}
+	var think, answer string
+	for _, chunk := range chunks {
+		t, a := p.Feed(chunk)
+		think += t
+		answer += a
+	}
+	t, a := p.Flush()
+	return think + t, answer + a
+}
+
+func TestThinkTagParser(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantThink  string
+		wantAnswer string
+	}{
+		{name: "no tag", input: "直接回答", wantAnswer: "直接回答"},
+		{name: "leading tag", input: "<think>想一想</think>回答", wantThink: "想一想", wantAnswer: "回答"},
+		{name: "leading whitespace before tag", input: "\n  <think>想</think>\n回答", wantThink: "想", wantAnswer: "\n回答"},
+		{name: "tag in the middle of the answer is kept", input: "用 <think> 标签包裹思考", wantAnswer: "用 <think> 标签包裹思考"},
+		{name: "second tag after think is kept", input: "<think>想</think>答<think>不是思考</think>", wantThink: "想", wantAnswer: "答<think>不是思考</think>"},
+		{name: "unterminated tag flushes as think", input: "<think>想到一半", wantThink: "想到一半"},
+		{name: "unterminated tag with partial close tag", input: "<think>想</thi", wantThink: "想</thi"},
+		{name: "partial open tag only", input: "<thi", wantAnswer: "<thi"},
+		{name: "whitespace only", input: " \n", wantAnswer: " \n"},
+		{name: "empty think", input: "<think></think>答", wantAnswer: "答"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 在每个字节处切分一次，覆盖标签被切分在两个 chunk 中的情况
+			for i := 0; i <= len(tt.input); i++ {
+				think, answer := feedAll([]string{tt.input[:i], tt.input[i:]})
+				if think != tt.wantThink || answer != tt.wantAnswer {
+					t.Fatalf("split at %d: got (%q, %q), want (%q, %q)", i, think, answer, tt.wantThink, tt.wantAnswer)
+				}
+			}
+			// 逐字节输入
+			chunks := make([]string, len(tt.input))
+			for i := 0; i < len(tt.input); i++ {
+				chunks[i] = tt.input[i : i+1]
+			}
+			think, answer := feedAll(chunks)
+			if think != tt.wantThink || answer != tt.wantAnswer {
+				t.Fatalf("byte by byte: got (%q, %q), want (%q, %q)", think, answer, tt.wantThink, tt.wantAnswer)
+			}
+		})
+	}
+}
+
+func TestThinkDemux(t *testing.T) {
+	req := &AiChatStreamRequest{Events: make(chan StreamEvent)}
+	var kinds []EventKind
+	var texts []string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range req.Events {
+			kinds = append(kinds, event.Kind)
+			texts = append(texts, event.Text)
+		}
+	}()
+	demux := newThinkDemux(req)
+	ctx := context.Background()
+	for _, chunk := range []string{"<thi", "nk>想", "</think", ">答", "案"} {
+		if err := demux.Write(ctx, chunk); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	if err := demux.Flush(ctx); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	close(req.Events)
+	<-done
+
+	wantKinds := []EventKind{EventThinkDelta, EventAnswerDelta, EventAnswerDelta}
+	wantTexts := []string{"想", "答", "案"}
+	if len(kinds) != len(wantKinds) {
+		t.Fatalf("events = %v %q, want %v %q", kinds, texts, wantKinds, wantTexts)
+	}
+	for i := range kinds {
+		if kinds[i] != wantKinds[i] || texts[i] != wantTexts[i] {
+			t.Fatalf("events = %v %q, want %v %q", kinds, texts, wantKinds, wantTexts)
+		}
+	}
+}
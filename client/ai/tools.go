@@ -0,0 +1,122 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// MaxToolRounds 单次请求中工具调用的最大轮数，避免模型反复调用陷入死循环
+	MaxToolRounds = 5
+)
+
+// ToolDefinition 工具定义，Parameters 为 JSON Schema
+type ToolDefinition struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+// ToolCall 模型发起的工具调用，结构与 OpenAI tool_calls 保持一致
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction 工具调用的函数名与 JSON 参数
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToolClient 支持工具调用的客户端，未实现该接口的客户端不会收到工具定义
+type ToolClient interface {
+	SupportsTools() bool
+}
+
+// supportsTools 判断客户端是否支持工具调用
+func supportsTools(client Client) bool {
+	tool, ok := client.(ToolClient)
+	return ok && tool.SupportsTools()
+}
+
+// Tool 定义可被模型调用的工具
+type Tool interface {
+	// Definition 获取工具定义
+	Definition() ToolDefinition
+
+	// Call 执行工具，arguments 为模型生成的 JSON 参数
+	Call(ctx context.Context, arguments string) (string, error)
+}
+
+// FuncTool 基于函数实现的工具
+type FuncTool struct {
+	Def ToolDefinition
+	Fn  func(ctx context.Context, arguments string) (string, error)
+}
+
+func (t *FuncTool) Definition() ToolDefinition {
+	return t.Def
+}
+
+func (t *FuncTool) Call(ctx context.Context, arguments string) (string, error) {
+	return t.Fn(ctx, arguments)
+}
+
+// ToolRegistry 工具注册表
+type ToolRegistry struct {
+	tools map[string]Tool
+	mu    sync.RWMutex
+}
+
+var (
+	toolRegistry *ToolRegistry
+	toolOnce     sync.Once
+)
+
+// GetToolRegistry 获取工具注册表单例
+func GetToolRegistry() *ToolRegistry {
+	toolOnce.Do(func() {
+		toolRegistry = &ToolRegistry{
+			tools: make(map[string]Tool),
+		}
+	})
+	return toolRegistry
+}
+
+// Register 注册工具，同名工具会被覆盖
+func (r *ToolRegistry) Register(tool Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tools[tool.Definition().Name] = tool
+}
+
+// Definitions 获取所有已注册工具的定义，按名称排序
+func (r *ToolRegistry) Definitions() []ToolDefinition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]ToolDefinition, 0, len(r.tools))
+	for _, tool := range r.tools {
+		defs = append(defs, tool.Definition())
+	}
+	sort.Slice(defs, func(i, j int) bool {
+		return defs[i].Name < defs[j].Name
+	})
+	return defs
+}
+
+// Call 执行指定名称的工具
+func (r *ToolRegistry) Call(ctx context.Context, name string, arguments string) (string, error) {
+	r.mu.RLock()
+	tool, ok := r.tools[name]
+	r.mu.RUnlock()
+
+	if !ok {
+		return "", fmt.Errorf("tool %s not registered", name)
+	}
+	return tool.Call(ctx, arguments)
+}
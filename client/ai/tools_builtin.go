@@ -0,0 +1,284 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	httpGetTimeout   = 10 * time.Second
+	httpGetMaxLength = 8000
+)
+
+// NewCurrentTimeTool 获取当前时间
+func NewCurrentTimeTool() Tool {
+	return &FuncTool{
+		Def: ToolDefinition{
+			Name:        "current_time",
+			Description: "获取当前日期和时间",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"timezone": map[string]any{
+						"type":        "string",
+						"description": "IANA 时区名称，如 Asia/Shanghai，默认 Asia/Shanghai",
+					},
+				},
+			},
+		},
+		Fn: func(ctx context.Context, arguments string) (string, error) {
+			var args struct {
+				Timezone string `json:"timezone"`
+			}
+			if err := unmarshalToolArgs(arguments, &args); err != nil {
+				return "", err
+			}
+			if args.Timezone == "" {
+				args.Timezone = "Asia/Shanghai"
+			}
+			loc, err := time.LoadLocation(args.Timezone)
+			if err != nil {
+				return "", fmt.Errorf("unknown timezone %s", args.Timezone)
+			}
+			now := time.Now().In(loc)
+			return fmt.Sprintf("%s %s", now.Format("2006-01-02 15:04:05 -07:00"), now.Weekday()), nil
+		},
+	}
+}
+
+// NewCalculatorTool 计算数学表达式
+func NewCalculatorTool() Tool {
+	return &FuncTool{
+		Def: ToolDefinition{
+			Name:        "calculator",
+			Description: "计算数学表达式，支持 + - * / % 括号以及 sqrt、pow、abs、floor、ceil、round、log、exp 函数",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"expression": map[string]any{
+						"type":        "string",
+						"description": "数学表达式，如 (1+2)*sqrt(16)",
+					},
+				},
+				"required": []string{"expression"},
+			},
+		},
+		Fn: func(ctx context.Context, arguments string) (string, error) {
+			var args struct {
+				Expression string `json:"expression"`
+			}
+			if err := unmarshalToolArgs(arguments, &args); err != nil {
+				return "", err
+			}
+			expr, err := parser.ParseExpr(args.Expression)
+			if err != nil {
+				return "", fmt.Errorf("invalid expression: %v", err)
+			}
+			result, err := evalExpr(expr)
+			if err != nil {
+				return "", err
+			}
+			return strconv.FormatFloat(result, 'g', -1, 64), nil
+		},
+	}
+}
+
+// NewHTTPGetTool 请求白名单内的 URL，allowlist 为域名列表，支持 *.example.com 形式
+func NewHTTPGetTool(allowlist []string) Tool {
+	httpClient := &http.Client{
+		Timeout: httpGetTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !hostAllowed(req.URL.Hostname(), allowlist) {
+				return fmt.Errorf("redirect to %s is not allowed", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+	return &FuncTool{
+		Def: ToolDefinition{
+			Name:        "http_get",
+			Description: "以 GET 方式请求 URL 并返回响应内容，仅允许访问白名单内的域名：" + strings.Join(allowlist, ", "),
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"url": map[string]any{
+						"type":        "string",
+						"description": "完整的 http 或 https URL",
+					},
+				},
+				"required": []string{"url"},
+			},
+		},
+		Fn: func(ctx context.Context, arguments string) (string, error) {
+			var args struct {
+				URL string `json:"url"`
+			}
+			if err := unmarshalToolArgs(arguments, &args); err != nil {
+				return "", err
+			}
+			u, err := url.Parse(args.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return "", fmt.Errorf("invalid url %s", args.URL)
+			}
+			if !hostAllowed(u.Hostname(), allowlist) {
+				return "", fmt.Errorf("host %s is not allowed", u.Hostname())
+			}
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+			if err != nil {
+				return "", err
+			}
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				return "", err
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(io.LimitReader(resp.Body, httpGetMaxLength))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("status: %d\n%s", resp.StatusCode, body), nil
+		},
+	}
+}
+
+// hostAllowed 判断域名是否在白名单内
+func hostAllowed(host string, allowlist []string) bool {
+	host = strings.ToLower(host)
+	for _, allowed := range allowlist {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// unmarshalToolArgs 解析工具参数，允许模型传入空参数
+func unmarshalToolArgs(arguments string, v any) error {
+	if strings.TrimSpace(arguments) == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(arguments), v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+// evalExpr 计算 go 语法的数学表达式，只支持数字、运算符和白名单函数
+func evalExpr(expr ast.Expr) (float64, error) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.INT && e.Kind != token.FLOAT {
+			return 0, fmt.Errorf("unsupported literal %s", e.Value)
+		}
+		return strconv.ParseFloat(e.Value, 64)
+	case *ast.ParenExpr:
+		return evalExpr(e.X)
+	case *ast.UnaryExpr:
+		x, err := evalExpr(e.X)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.SUB:
+			return -x, nil
+		case token.ADD:
+			return x, nil
+		}
+		return 0, fmt.Errorf("unsupported operator %s", e.Op)
+	case *ast.BinaryExpr:
+		x, err := evalExpr(e.X)
+		if err != nil {
+			return 0, err
+		}
+		y, err := evalExpr(e.Y)
+		if err != nil {
+			return 0, err
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, nil
+		case token.SUB:
+			return x - y, nil
+		case token.MUL:
+			return x * y, nil
+		case token.QUO:
+			if y == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return x / y, nil
+		case token.REM:
+			if y == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return math.Mod(x, y), nil
+		}
+		return 0, fmt.Errorf("unsupported operator %s", e.Op)
+	case *ast.Ident:
+		switch e.Name {
+		case "pi":
+			return math.Pi, nil
+		case "e":
+			return math.E, nil
+		}
+		return 0, fmt.Errorf("unknown identifier %s", e.Name)
+	case *ast.CallExpr:
+		fn, ok := e.Fun.(*ast.Ident)
+		if !ok {
+			return 0, fmt.Errorf("unsupported function call")
+		}
+		args := make([]float64, len(e.Args))
+		for i, arg := range e.Args {
+			v, err := evalExpr(arg)
+			if err != nil {
+				return 0, err
+			}
+			args[i] = v
+		}
+		return callMathFunc(fn.Name, args)
+	}
+	return 0, fmt.Errorf("unsupported expression")
+}
+
+// callMathFunc 调用白名单内的数学函数
+func callMathFunc(name string, args []float64) (float64, error) {
+	unary := map[string]func(float64) float64{
+		"sqrt":  math.Sqrt,
+		"abs":   math.Abs,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"log":   math.Log,
+		"exp":   math.Exp,
+	}
+	if fn, ok := unary[name]; ok {
+		if len(args) != 1 {
+			return 0, fmt.Errorf("%s expects 1 argument", name)
+		}
+		return fn(args[0]), nil
+	}
+	if name == "pow" {
+		if len(args) != 2 {
+			return 0, fmt.Errorf("pow expects 2 arguments")
+		}
+		return math.Pow(args[0], args[1]), nil
+	}
+	return 0, fmt.Errorf("unknown function %s", name)
+}
@@ -0,0 +1,84 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCalculatorTool(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		want       string
+		wantErr    string
+	}{
+		{name: "precedence", expression: "1+2*3", want: "7"},
+		{name: "parens and functions", expression: "(1+2)*sqrt(16)", want: "12"},
+		{name: "unary and float", expression: "-1.5+pow(2, 3)", want: "6.5"},
+		{name: "constant", expression: "floor(pi)", want: "3"},
+		{name: "remainder", expression: "7 % 4", want: "3"},
+		{name: "division by zero", expression: "1/0", wantErr: "division by zero"},
+		{name: "division by zero expression", expression: "1/(2-2)", wantErr: "division by zero"},
+		{name: "remainder by zero", expression: "5 % 0", wantErr: "division by zero"},
+		{name: "incomplete", expression: "1+", wantErr: "invalid expression"},
+		{name: "unbalanced parens", expression: "(1+2", wantErr: "invalid expression"},
+		{name: "empty", expression: "", wantErr: "invalid expression"},
+		{name: "string literal", expression: `"1"+1`, wantErr: "unsupported literal"},
+		{name: "unknown identifier", expression: "x+1", wantErr: "unknown identifier x"},
+		{name: "unknown function", expression: "os(1)", wantErr: "unknown function os"},
+		{name: "selector call", expression: "math.Sqrt(4)", wantErr: "unsupported function call"},
+		{name: "wrong argument count", expression: "sqrt(1, 2)", wantErr: "sqrt expects 1 argument"},
+		{name: "bitwise operator", expression: "1 << 2", wantErr: "unsupported operator"},
+		{name: "index expression", expression: "a[0]", wantErr: "unsupported expression"},
+	}
+	tool := NewCalculatorTool()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, _ := json.Marshal(map[string]string{"expression": tt.expression})
+			got, err := tool.Call(context.Background(), string(args))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Call(%q) error = %v, want %q", tt.expression, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Call(%q) error = %v", tt.expression, err)
+			}
+			if got != tt.want {
+				t.Errorf("Call(%q) = %s, want %s", tt.expression, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculatorToolInvalidArguments(t *testing.T) {
+	if _, err := NewCalculatorTool().Call(context.Background(), "{expression"); err == nil || !strings.Contains(err.Error(), "invalid arguments") {
+		t.Errorf("Call() error = %v, want invalid arguments", err)
+	}
+}
+
+func TestHostAllowed(t *testing.T) {
+	allowlist := []string{"api.github.com", "*.example.com"}
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "api.github.com", want: true},
+		{host: "API.GitHub.com", want: true},
+		{host: "github.com", want: false},
+		{host: "docs.example.com", want: true},
+		{host: "a.b.example.com", want: true},
+		{host: "example.com", want: false},
+		{host: "evilexample.com", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := hostAllowed(tt.host, allowlist); got != tt.want {
+				t.Errorf("hostAllowed(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
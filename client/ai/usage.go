@@ -0,0 +1,23 @@
+package ai
+
+// Usage 单次请求的 token 用量
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	ReasoningTokens  int `json:"reasoning_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// Estimated 模型服务未返回用量，由本地按内容估算
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// Add 累加用量，用于多轮工具调用等场景
+func (u *Usage) Add(other *Usage) {
+	if other == nil {
+		return
+	}
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.ReasoningTokens += other.ReasoningTokens
+	u.TotalTokens += other.TotalTokens
+	u.Estimated = u.Estimated || other.Estimated
+}
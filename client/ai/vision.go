@@ -0,0 +1,107 @@
+package ai
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// 多模态内容片段类型，与 OpenAI content 数组一致
+const (
+	ContentPartText  = "text"
+	ContentPartImage = "image_url"
+)
+
+// ContentPart 多模态消息的内容片段
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL 图片地址，支持 http 地址和 data:image/png;base64,... 形式的内联图片
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// VisionClient 支持图片输入的客户端
+type VisionClient interface {
+	// SupportsVision 指定模型是否支持图片输入
+	SupportsVision(model string) bool
+}
+
+func NewTextPart(text string) ContentPart {
+	return ContentPart{Type: ContentPartText, Text: text}
+}
+
+// NewImagePart 将图片内容编码为 data URL
+func NewImagePart(data []byte) ContentPart {
+	mediaType := http.DetectContentType(data)
+	return ContentPart{
+		Type:     ContentPartImage,
+		ImageURL: &ImageURL{URL: "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)},
+	}
+}
+
+// ImageData 解析内联图片，返回媒体类型和 base64 数据，非内联图片返回 false
+func (p ContentPart) ImageData() (string, string, bool) {
+	if p.Type != ContentPartImage || p.ImageURL == nil {
+		return "", "", false
+	}
+	rest, ok := strings.CutPrefix(p.ImageURL.URL, "data:")
+	if !ok {
+		return "", "", false
+	}
+	return strings.Cut(rest, ";base64,")
+}
+
+// Images 返回消息中的图片片段
+func (m AiMessage) Images() []ContentPart {
+	var images []ContentPart
+	for _, p := range m.Parts {
+		if p.Type == ContentPartImage {
+			images = append(images, p)
+		}
+	}
+	return images
+}
+
+// MarshalJSON Parts 非空时 content 序列化为 OpenAI 格式的内容数组
+func (m AiMessage) MarshalJSON() ([]byte, error) {
+	type message AiMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(message(m))
+	}
+	return json.Marshal(struct {
+		message
+		Content []ContentPart `json:"content"`
+	}{message(m), m.Parts})
+}
+
+// HasImages 消息列表中是否包含图片
+func HasImages(msgs []AiMessage) bool {
+	return slices.ContainsFunc(msgs, func(m AiMessage) bool {
+		return len(m.Images()) > 0
+	})
+}
+
+// TextOnly 返回去掉图片的消息副本，用于不支持图片的模型和日志输出
+func TextOnly(msgs []AiMessage) []AiMessage {
+	if !HasImages(msgs) {
+		return msgs
+	}
+	result := make([]AiMessage, len(msgs))
+	for i, m := range msgs {
+		m.Parts = nil
+		result[i] = m
+	}
+	return result
+}
+
+// supportsVision 判断客户端是否支持指定模型的图片输入
+func supportsVision(client Client, model string) bool {
+	vision, ok := client.(VisionClient)
+	return ok && vision.SupportsVision(model)
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 
 	"github.com/cloudwego/hertz/pkg/common/hlog"
 	"github.com/volcengine/volcengine-go-sdk/service/arkruntime"
@@ -38,53 +39,265 @@ func (c *VolcClient) GetProvider() Provider {
 	return ProviderVolc
 }
 
+func (c *VolcClient) GetModels() []string {
+	return configuredModels(c.cfg.Model, c.cfg.Models)
+}
+
+func (c *VolcClient) SupportsVision(model string) bool {
+	return slices.Contains(c.cfg.VisionModels, model)
+}
+
+// SupportsTools 只有 model 模式支持工具调用，Bot API 的插件在应用中配置
+func (c *VolcClient) SupportsTools() bool {
+	return c.cfg.Mode == config.VolcModeModel
+}
+
 func (c *VolcClient) StreamChat(ctx context.Context, req *AiChatStreamRequest) error {
 	chatMsgs := make([]*model.ChatCompletionMessage, len(req.Msgs))
 	for i, m := range req.Msgs {
 		chatMsgs[i] = &model.ChatCompletionMessage{
-			Role:    m.Role,
-			Content: &model.ChatCompletionMessageContent{StringValue: volcengine.String(m.Content)},
+			Role:       m.Role,
+			Content:    volcContent(m),
+			ToolCallID: m.ToolCallId,
+		}
+		for _, call := range m.ToolCalls {
+			chatMsgs[i].ToolCalls = append(chatMsgs[i].ToolCalls, &model.ToolCall{
+				ID:       call.ID,
+				Type:     model.ToolTypeFunction,
+				Function: model.FunctionCall{Name: call.Function.Name, Arguments: call.Function.Arguments},
+			})
 		}
 	}
-	return c.StreamChatWithHistory(ctx, chatMsgs, MaxTokens, req.ThinkStream, req.AnswerStream, req.RefStream)
+	return c.streamChat(ctx, modelOrDefault(req, c.cfg.Model), chatMsgs, c.GetGeneration().Merge(req.Params), req)
+}
+
+// StreamChatWithHistory 使用方舟原生消息对话，events 只会收到思考、回答与引用事件，由调用方创建和关闭
+func (c *VolcClient) StreamChatWithHistory(ctx context.Context, modelId string, msg []*model.ChatCompletionMessage, maxTokens int, events chan StreamEvent) error {
+	params := c.GetGeneration().Merge(&config.GenerationConfig{MaxTokens: maxTokens})
+	return c.streamChat(ctx, modelId, msg, params, &AiChatStreamRequest{Events: events})
+}
+
+// GetGeneration 未配置 temperature 和 top_p 时沿用 0.7 和 1
+// 方舟 SDK 暂不支持 seed 和 reasoning_effort，配置后会被忽略
+func (c *VolcClient) GetGeneration() config.GenerationConfig {
+	g := withGenerationDefaults(c.cfg.Generation)
+	if g.Temperature == nil {
+		g.Temperature = volcengine.Float64(0.7)
+	}
+	if g.TopP == nil {
+		g.TopP = volcengine.Float64(1)
+	}
+	return g
 }
 
-func (c *VolcClient) StreamChatWithHistory(ctx context.Context, msg []*model.ChatCompletionMessage, maxTokens int, thinkStream, answerStream, refStream chan string) error {
+// Embed 调用向量化接入点，需配置 embedding_model
+func (c *VolcClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if c.cfg.EmbeddingModel == "" {
+		return nil, fmt.Errorf("volc embedding_model not configured")
+	}
+	resp, err := c.client.CreateEmbeddings(ctx, model.EmbeddingRequestStrings{
+		Input: texts,
+		Model: c.cfg.EmbeddingModel,
+	})
+	if err != nil {
+		hlog.Errorf("CreateEmbeddings returned error: %v", err)
+		return nil, err
+	}
+	vectors := make([][]float32, len(resp.Data))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(vectors) {
+			return nil, fmt.Errorf("volc embeddings index %d out of range", d.Index)
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return checkEmbeddings(vectors, texts)
+}
+
+// volcContent 转换消息内容，包含图片时使用内容数组
+func volcContent(m AiMessage) *model.ChatCompletionMessageContent {
+	if len(m.Parts) == 0 {
+		return &model.ChatCompletionMessageContent{StringValue: volcengine.String(m.Content)}
+	}
+	parts := make([]*model.ChatCompletionMessageContentPart, 0, len(m.Parts))
+	for _, p := range m.Parts {
+		part := &model.ChatCompletionMessageContentPart{
+			Type: model.ChatCompletionMessageContentPartType(p.Type),
+			Text: p.Text,
+		}
+		if p.ImageURL != nil {
+			part.ImageURL = &model.ChatMessageImageURL{URL: p.ImageURL.URL}
+		}
+		parts = append(parts, part)
+	}
+	return &model.ChatCompletionMessageContent{ListValue: parts}
+}
+
+// streamChat 按配置的模式调用，工具调用与用量回填到 req
+func (c *VolcClient) streamChat(ctx context.Context, modelId string, msg []*model.ChatCompletionMessage, params config.GenerationConfig, req *AiChatStreamRequest) error {
+	if c.cfg.Mode == config.VolcModeModel {
+		return c.streamModelChat(ctx, modelId, msg, params, req)
+	}
+	return c.streamBotChat(ctx, modelId, msg, params, req)
+}
+
+// streamBotChat 通过 Bot API 对话，支持联网搜索等插件返回的参考文献
+// Bot API 的请求中取值为 0 的参数不会发送，temperature 为 0 时使用应用的默认值
+func (c *VolcClient) streamBotChat(ctx context.Context, botId string, msg []*model.ChatCompletionMessage, params config.GenerationConfig, chatReq *AiChatStreamRequest) error {
 	req := model.BotChatCompletionRequest{
-		BotId:       c.cfg.Model,
-		Messages:    msg,
-		N:           1,
-		Temperature: 0.7,
-		MaxTokens:   maxTokens,
-		TopP:        1,
+		BotId:           botId,
+		Messages:        msg,
+		N:               1,
+		Temperature:     float32(volcengine.Float64Value(params.Temperature)),
+		MaxTokens:       params.MaxTokens,
+		TopP:            float32(volcengine.Float64Value(params.TopP)),
+		Stop:            params.Stop,
+		PresencePenalty: float32(volcengine.Float64Value(params.PresencePenalty)),
+		StreamOptions:   &model.StreamOptions{IncludeUsage: true},
 	}
 	stream, err := c.client.CreateBotChatCompletionStream(ctx, req)
 	if err != nil {
 		hlog.Errorf("CreateBotChatCompletionStream returned error: %v", err)
-		return err
+		return wrapVolcError(ctx, err)
 	}
 	defer stream.Close()
+	demux := newThinkDemux(chatReq)
 	for {
 		response, err := stream.Recv()
 		if errors.Is(err, io.EOF) {
-			return nil
+			return demux.Flush(ctx)
 		}
 		if err != nil {
 			hlog.Errorf("Stream error: %v\n", err)
-			return err
+			return wrapVolcError(ctx, err)
+		}
+		if response.Usage != nil {
+			chatReq.Usage = convertVolcUsage(response.Usage)
+		} else if response.BotUsage != nil {
+			chatReq.Usage = convertVolcBotUsage(response.BotUsage)
 		}
 		if len(response.Choices) > 0 {
-			if response.References != nil {
-				for i, ref := range response.References {
-					num := i + 1
-					refStream <- fmt.Sprintf("[%d] [%s](%s)\n", num, ref.Title, ref.Url)
+			if reason := response.Choices[0].FinishReason; reason != "" {
+				chatReq.FinishReason = string(reason)
+			}
+			// 每个 chunk 都会携带完整的引用列表，由使用方去重
+			for _, ref := range response.References {
+				if err := chatReq.emitReference(ctx, volcReference(ref)); err != nil {
+					return err
 				}
 			}
-			if response.Choices[0].Delta.ReasoningContent != nil {
-				thinkStream <- *response.Choices[0].Delta.ReasoningContent
-			} else {
-				answerStream <- response.Choices[0].Delta.Content
+			if reasoning := response.Choices[0].Delta.ReasoningContent; reasoning != nil {
+				if err := chatReq.emitThink(ctx, *reasoning); err != nil {
+					return err
+				}
+			} else if err := demux.Write(ctx, response.Choices[0].Delta.Content); err != nil {
+				return err
 			}
 		}
 	}
 }
+
+// streamModelChat 直接调用推理接入点（模型 ID 或 ep-xxx），无需在控制台创建应用
+// 使用 CreateChatCompletionRequest，temperature 为 0 时也会发送
+func (c *VolcClient) streamModelChat(ctx context.Context, modelId string, msg []*model.ChatCompletionMessage, params config.GenerationConfig, chatReq *AiChatStreamRequest) error {
+	req := model.CreateChatCompletionRequest{
+		Model:           modelId,
+		Messages:        msg,
+		N:               volcengine.Int(1),
+		Temperature:     float32Ptr(params.Temperature),
+		MaxTokens:       volcengine.Int(params.MaxTokens),
+		TopP:            float32Ptr(params.TopP),
+		Stop:            params.Stop,
+		PresencePenalty: float32Ptr(params.PresencePenalty),
+		StreamOptions:   &model.StreamOptions{IncludeUsage: true},
+	}
+	for _, def := range chatReq.Tools {
+		req.Tools = append(req.Tools, &model.Tool{
+			Type:     model.ToolTypeFunction,
+			Function: &model.FunctionDefinition{Name: def.Name, Description: def.Description, Parameters: def.Parameters},
+		})
+	}
+	stream, err := c.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		hlog.Errorf("CreateChatCompletionStream returned error: %v", err)
+		return wrapVolcError(ctx, err)
+	}
+	defer stream.Close()
+	demux := newThinkDemux(chatReq)
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return demux.Flush(ctx)
+		}
+		if err != nil {
+			hlog.Errorf("Stream error: %v\n", err)
+			return wrapVolcError(ctx, err)
+		}
+		if response.Usage != nil {
+			chatReq.Usage = convertVolcUsage(response.Usage)
+		}
+		if len(response.Choices) > 0 {
+			if reason := response.Choices[0].FinishReason; reason != "" {
+				chatReq.FinishReason = string(reason)
+			}
+			// 工具调用参数分多个 chunk 返回，带 ID 的为新调用，其余拼接到上一个
+			for _, call := range response.Choices[0].Delta.ToolCalls {
+				if call.ID != "" || len(chatReq.ToolCalls) == 0 {
+					chatReq.ToolCalls = append(chatReq.ToolCalls, ToolCall{ID: call.ID, Type: "function"})
+				}
+				last := &chatReq.ToolCalls[len(chatReq.ToolCalls)-1]
+				last.Function.Name += call.Function.Name
+				last.Function.Arguments += call.Function.Arguments
+			}
+			if reasoning := response.Choices[0].Delta.ReasoningContent; reasoning != nil {
+				if err := chatReq.emitThink(ctx, *reasoning); err != nil {
+					return err
+				}
+			} else if err := demux.Write(ctx, response.Choices[0].Delta.Content); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// volcReference 转换联网搜索或知识库插件返回的引用
+func volcReference(ref *model.BotChatResultReference) Reference {
+	r := Reference{
+		Title:   ref.Title,
+		URL:     ref.Url,
+		Site:    ref.SiteName,
+		Snippet: ref.Summary,
+	}
+	if r.Title == "" {
+		r.Title = ref.DocName
+	}
+	if ref.CoverImage != nil {
+		r.Cover = ref.CoverImage.Url
+	}
+	return r
+}
+
+func float32Ptr(v *float64) *float32 {
+	if v == nil {
+		return nil
+	}
+	return volcengine.Float32(float32(*v))
+}
+
+// convertVolcUsage 转换方舟返回的用量
+func convertVolcUsage(usage *model.Usage) *Usage {
+	return &Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		ReasoningTokens:  usage.CompletionTokensDetails.ReasoningTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+// convertVolcBotUsage 汇总 Bot 中各模型调用的用量
+func convertVolcBotUsage(botUsage *model.BotUsage) *Usage {
+	usage := &Usage{}
+	for _, m := range botUsage.ModelUsage {
+		usage.Add(convertVolcUsage(&m.Usage))
+	}
+	return usage
+}
@@ -2,11 +2,20 @@ package im
 
 import (
 	"ai-stream-bot/config"
+	"ai-stream-bot/consts"
 	"ai-stream-bot/model"
 	"ai-stream-bot/pkg"
+	"ai-stream-bot/pkg/feishu"
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/cloudwego/hertz/pkg/common/hlog"
@@ -23,8 +32,26 @@ var (
 	feishuClient *FeishuClient
 )
 
+// ErrResourceTooLarge 消息中的图片或文件超过读取上限
+var ErrResourceTooLarge = errors.New("message resource too large")
+
+// StopButtonElementId 流式卡片中停止按钮的组件 ID
+const StopButtonElementId = "stop"
+
+// resourceDownloadTimeout 下载消息资源的超时时间
+const resourceDownloadTimeout = 2 * time.Minute
+
 type FeishuClient struct {
 	*lark.Client
+	appId     string
+	appSecret string
+	// baseURL 开放平台地址，消息资源直接请求接口下载，SDK 会将响应整体读入内存
+	baseURL    string
+	httpClient *http.Client
+
+	tokenMu     sync.Mutex
+	token       string
+	tokenExpire time.Time
 }
 
 func NewFeishuClient(cfg *config.FeishuConfig, eventHandler *dispatcher.EventDispatcher) {
@@ -34,7 +61,11 @@ func NewFeishuClient(cfg *config.FeishuConfig, eventHandler *dispatcher.EventDis
 		larkws.WithLogLevel(larkcore.LogLevelDebug))
 
 	feishuClient = &FeishuClient{
-		larkClient,
+		Client:     larkClient,
+		appId:      cfg.AppID,
+		appSecret:  cfg.AppSecret,
+		baseURL:    lark.FeishuBaseUrl,
+		httpClient: &http.Client{Timeout: resourceDownloadTimeout},
 	}
 	// 启动飞书 WebSocket 连接
 	go func() {
@@ -115,6 +146,14 @@ func (f *FeishuClient) FeishuCreateCard(ctx context.Context) (*string, error) {
                 "margin": "0px 0px 0px 0px",
                 "element_id": "think"
             },
+            {
+                "tag": "markdown",
+                "content": "",
+                "text_align": "left",
+                "text_size": "notation",
+                "margin": "0px 0px 0px 0px",
+                "element_id": "tool"
+            },
             {
                 "tag": "markdown",
                 "content": "",
@@ -149,9 +188,72 @@ func (f *FeishuClient) FeishuCreateCard(ctx context.Context) (*string, error) {
 		hlog.Errorf("FeishuCreateCard returned error: %v", resp.Code, resp.Msg, resp.RequestId())
 		return nil, errors.New(resp.Msg)
 	}
+	// 停止按钮的回调需要携带卡片 ID，只能在卡片创建后追加
+	stopButton, _ := json.Marshal([]map[string]interface{}{
+		feishu.BuildStreamCardButton(StopButtonElementId, "停止生成", "default", map[string]interface{}{
+			"kind":   consts.StopCard,
+			"cardId": *resp.Data.CardId,
+		}),
+	})
+	if err := f.FeishuAppendCardElements(ctx, *resp.Data.CardId, string(stopButton)); err != nil {
+		hlog.Errorf("append stop button error: %v", err)
+	}
 	return resp.Data.CardId, nil
 }
 
+// FeishuAppendCardElements 在卡片末尾追加组件，elements 为组件数组的 JSON
+func (f *FeishuClient) FeishuAppendCardElements(ctx context.Context, cardId string, elements string) error {
+	req := larkcardkit.NewCreateCardElementReqBuilder().
+		CardId(cardId).
+		Body(larkcardkit.NewCreateCardElementReqBodyBuilder().
+			Type(`append`).
+			Elements(elements).
+			Uuid(uuid.New().String()).
+			Sequence(pkg.NextSequence()).
+			Build()).
+		Build()
+
+	// 发起请求
+	resp, err := f.Client.Cardkit.V1.CardElement.Create(ctx, req)
+
+	// 处理错误
+	if err != nil {
+		return err
+	}
+
+	// 服务端错误处理
+	if !resp.Success() {
+		return errors.New(resp.Msg)
+	}
+	return nil
+}
+
+// FeishuDeleteCardElement 删除卡片中的组件
+func (f *FeishuClient) FeishuDeleteCardElement(ctx context.Context, cardId string, elementId string) error {
+	req := larkcardkit.NewDeleteCardElementReqBuilder().
+		CardId(cardId).
+		ElementId(elementId).
+		Body(larkcardkit.NewDeleteCardElementReqBodyBuilder().
+			Uuid(uuid.New().String()).
+			Sequence(pkg.NextSequence()).
+			Build()).
+		Build()
+
+	// 发起请求
+	resp, err := f.Client.Cardkit.V1.CardElement.Delete(ctx, req)
+
+	// 处理错误
+	if err != nil {
+		return err
+	}
+
+	// 服务端错误处理
+	if !resp.Success() {
+		return errors.New(resp.Msg)
+	}
+	return nil
+}
+
 func (f *FeishuClient) FeishuUpdateCard(ctx context.Context, update model.StreamUpdateMessage, cardId string) error {
 	var resp *larkcardkit.ContentCardElementResp
 	var err error
@@ -172,6 +274,21 @@ func (f *FeishuClient) FeishuUpdateCard(ctx context.Context, update model.Stream
 		resp, err = f.Client.Cardkit.V1.CardElement.Content(ctx, req)
 		needRequest = true
 	}
+	if update.Tool != "" {
+		// 创建请求对象
+		req := larkcardkit.NewContentCardElementReqBuilder().
+			CardId(cardId).
+			ElementId(`tool`).
+			Body(larkcardkit.NewContentCardElementReqBodyBuilder().
+				Uuid(uuid.New().String()).
+				Content(update.Tool).
+				Sequence(pkg.NextSequence()).
+				Build()).
+			Build()
+		// 发起请求
+		resp, err = f.Client.Cardkit.V1.CardElement.Content(ctx, req)
+		needRequest = true
+	}
 	if update.Answer != "" {
 		// 创建请求对象
 		req := larkcardkit.NewContentCardElementReqBuilder().
@@ -245,3 +362,109 @@ func (f *FeishuClient) FeishuUpdateCardSetting(ctx context.Context, cardId strin
 	}
 	return nil
 }
+
+// FeishuGetChatOwner 获取群主的 user_id，群主为机器人时返回空
+func (f *FeishuClient) FeishuGetChatOwner(ctx context.Context, chatId string) (string, error) {
+	req := larkim.NewGetChatReqBuilder().
+		ChatId(chatId).
+		UserIdType(larkim.UserIdTypeGetChatUserId).
+		Build()
+
+	// 发起请求
+	resp, err := f.Client.Im.Chat.Get(ctx, req)
+
+	// 处理错误
+	if err != nil {
+		hlog.Errorf("FeishuGetChatOwner returned error: %v", err)
+		return "", err
+	}
+
+	// 服务端错误处理
+	if !resp.Success() {
+		hlog.Errorf("FeishuGetChatOwner returned error: %v, %v, %v", resp.Code, resp.Msg, resp.RequestId())
+		return "", errors.New(resp.Msg)
+	}
+	if resp.Data.OwnerId == nil {
+		return "", nil
+	}
+	return *resp.Data.OwnerId, nil
+}
+
+// FeishuGetMessageResource 下载消息中的图片或文件，resType 为 image 或 file
+// Content-Length 超过 maxSize 时不读取响应，否则边读边计数，超出时返回 ErrResourceTooLarge
+func (f *FeishuClient) FeishuGetMessageResource(ctx context.Context, msgId string, fileKey string, resType string, maxSize int) ([]byte, error) {
+	token, err := f.tenantAccessToken(ctx)
+	if err != nil {
+		hlog.Errorf("FeishuGetMessageResource get token error: %v", err)
+		return nil, err
+	}
+	resourceURL := fmt.Sprintf("%s/open-apis/im/v1/messages/%s/resources/%s?type=%s",
+		f.baseURL, url.PathEscape(msgId), url.PathEscape(fileKey), url.QueryEscape(resType))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, resourceURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	// 发起请求
+	resp, err := f.httpClient.Do(req)
+	if err != nil {
+		hlog.Errorf("FeishuGetMessageResource returned error: %v", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 服务端错误以 JSON 返回
+	if resp.StatusCode != http.StatusOK || strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		codeErr := larkcore.CodeError{}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(body, &codeErr) != nil || codeErr.Msg == "" {
+			codeErr.Msg = fmt.Sprintf("status %d", resp.StatusCode)
+		}
+		hlog.Errorf("FeishuGetMessageResource returned error: %v, %v, %v", resp.StatusCode, codeErr.Code, codeErr.Msg)
+		// token 可能已失效，下次请求重新获取
+		f.resetToken(token)
+		return nil, errors.New(codeErr.Msg)
+	}
+	if resp.ContentLength > int64(maxSize) {
+		return nil, ErrResourceTooLarge
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSize {
+		return nil, ErrResourceTooLarge
+	}
+	return data, nil
+}
+
+// tenantAccessToken 获取 tenant_access_token，过期前 5 分钟刷新
+func (f *FeishuClient) tenantAccessToken(ctx context.Context) (string, error) {
+	f.tokenMu.Lock()
+	defer f.tokenMu.Unlock()
+	if f.token != "" && time.Now().Before(f.tokenExpire) {
+		return f.token, nil
+	}
+	resp, err := f.Client.GetTenantAccessTokenBySelfBuiltApp(ctx, &larkcore.SelfBuiltTenantAccessTokenReq{
+		AppID:     f.appId,
+		AppSecret: f.appSecret,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success() {
+		return "", errors.New(resp.Msg)
+	}
+	f.token = resp.TenantAccessToken
+	f.tokenExpire = time.Now().Add(time.Duration(resp.Expire)*time.Second - 5*time.Minute)
+	return f.token, nil
+}
+
+func (f *FeishuClient) resetToken(token string) {
+	f.tokenMu.Lock()
+	defer f.tokenMu.Unlock()
+	if f.token == token {
+		f.token = ""
+	}
+}
@@ -0,0 +1,109 @@
+package im
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestFeishuClient(t *testing.T, handler http.HandlerFunc) *FeishuClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return &FeishuClient{
+		baseURL:     server.URL,
+		httpClient:  server.Client(),
+		token:       "t-test",
+		tokenExpire: time.Now().Add(time.Hour),
+	}
+}
+
+func TestFeishuGetMessageResource(t *testing.T) {
+	const maxSize = 16
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		want    string
+		wantErr string
+	}{
+		{
+			name: "ok",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/open-apis/im/v1/messages/om_1/resources/file_1" || r.URL.Query().Get("type") != "file" {
+					t.Errorf("unexpected request %s", r.URL)
+				}
+				if r.Header.Get("Authorization") != "Bearer t-test" {
+					t.Errorf("Authorization = %s", r.Header.Get("Authorization"))
+				}
+				w.Header().Set("Content-Type", "application/octet-stream")
+				_, _ = w.Write([]byte("hello"))
+			},
+			want: "hello",
+		},
+		{
+			name: "content length over limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				w.Header().Set("Content-Length", strconv.Itoa(1<<30))
+				_, _ = w.Write([]byte("partial"))
+			},
+			wantErr: ErrResourceTooLarge.Error(),
+		},
+		{
+			name: "chunked body over limit",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/octet-stream")
+				for i := 0; i < 4; i++ {
+					_, _ = w.Write([]byte(strings.Repeat("x", maxSize)))
+					w.(http.Flusher).Flush()
+				}
+			},
+			wantErr: ErrResourceTooLarge.Error(),
+		},
+		{
+			name: "api error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json; charset=utf-8")
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(`{"code":234003,"msg":"File not in msg."}`))
+			},
+			wantErr: "File not in msg.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestFeishuClient(t, tt.handler)
+			data, err := client.FeishuGetMessageResource(context.Background(), "om_1", "file_1", "file", maxSize)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("data = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeishuGetMessageResourceResetsTokenOnError(t *testing.T) {
+	client := newTestFeishuClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"code":99991663,"msg":"Invalid access token"}`))
+	})
+	if _, err := client.FeishuGetMessageResource(context.Background(), "om_1", "img_1", "image", 16); err == nil || errors.Is(err, ErrResourceTooLarge) {
+		t.Fatalf("error = %v, want api error", err)
+	}
+	if client.token != "" {
+		t.Errorf("token = %q, want reset", client.token)
+	}
+}
@@ -1,9 +1,13 @@
 package config
 
 import (
+	"ai-stream-bot/consts"
 	"fmt"
 	"os"
 	"reflect"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/cloudwego/hertz/pkg/common/hlog"
@@ -17,8 +21,37 @@ var (
 
 // Config 总配置结构
 type Config struct {
-	Bot *BotConfig `yaml:"bot"`
-	AI  *AIConfig  `yaml:"ai"`
+	Bot    *BotConfig    `yaml:"bot"`
+	AI     *AIConfig     `yaml:"ai"`
+	Tools  *ToolsConfig  `yaml:"tools"`
+	Prompt *PromptConfig `yaml:"prompt"`
+	KB     *KBConfig     `yaml:"kb"`
+}
+
+// KBConfig 知识库配置，知识库按群聊隔离
+type KBConfig struct {
+	Enable      bool    `yaml:"enable"`
+	Path        string  `yaml:"path"`         // 存储目录，默认 data/kb
+	TopK        int     `yaml:"top_k"`        // 每次检索的分块数，默认 4
+	MinScore    float64 `yaml:"min_score"`    // 相似度低于该值的分块不使用
+	ChunkTokens int     `yaml:"chunk_tokens"` // 分块 token 数，默认 500
+	Embedder    string  `yaml:"embedder"`     // 提供向量化的服务名称，为空时按关键词检索
+}
+
+// PromptConfig 默认 system prompt 配置，优先级：/persona > chats > group/personal > default
+type PromptConfig struct {
+	Default  string            `yaml:"default"`
+	Group    string            `yaml:"group"`    // 群聊默认
+	Personal string            `yaml:"personal"` // 单聊默认
+	Chats    map[string]string `yaml:"chats"`    // 按 chat_id 配置
+	Admins   []string          `yaml:"admins"`   // 可在任意群聊中修改人设的 user_id，群主始终可以修改
+	Path     string            `yaml:"path"`     // /persona 设置的人设保存文件，默认 data/persona.json
+}
+
+// ToolsConfig 工具调用配置
+type ToolsConfig struct {
+	Enable        bool     `yaml:"enable"`
+	HTTPAllowlist []string `yaml:"http_allowlist"` // http_get 工具允许访问的域名，为空时不注册该工具
 }
 
 // BotConfig 机器人配置
@@ -50,24 +83,211 @@ type DingtalkConfig struct {
 
 // AIConfig AI配置
 type AIConfig struct {
-	OpenAI *OpenAIConfig `yaml:"openai"`
-	Volc   *VolcConfig   `yaml:"volc"`
+	OpenAI    *OpenAIConfig    `yaml:"openai"`
+	Volc      *VolcConfig      `yaml:"volc"`
+	Anthropic *AnthropicConfig `yaml:"anthropic"`
+	Ollama    *OllamaConfig    `yaml:"ollama"`
+	Mock      *MockConfig      `yaml:"mock"`
+	// Default 默认服务，启用多个服务时需配置 default 或 fallback
+	Default string `yaml:"default"`
+	// Fallback 故障转移顺序，如 [volc, openai]，首个为默认服务
+	Fallback []string `yaml:"fallback"`
+	// Routes 模型路由规则，按顺序匹配，命中第一条即停止
+	Routes []*RouteRule `yaml:"routes"`
+	// Instances 具名实例，同一类型的服务可配置多个
+	Instances []*InstanceConfig `yaml:"instances"`
+	// Context 上下文窗口配置
+	Context *ContextConfig `yaml:"context"`
+}
+
+// ContextConfig 上下文窗口配置，models 中未配置的模型使用默认值
+type ContextConfig struct {
+	ContextWindow int                            `yaml:"context_window"` // 默认上下文窗口 token 数
+	ReserveOutput int                            `yaml:"reserve_output"` // 默认为输出预留的 token 数
+	Truncate      string                         `yaml:"truncate"`       // 裁剪策略：sliding_window、drop_oldest_pairs、last_turns、summarize
+	KeepTurns     int                            `yaml:"keep_turns"`     // last_turns 策略保留的轮数
+	Summary       *SummaryConfig                 `yaml:"summary"`        // summarize 策略配置
+	Models        map[string]*ModelContextConfig `yaml:"models"`
+}
+
+// SummaryConfig 对话摘要配置
+type SummaryConfig struct {
+	Threshold int    `yaml:"threshold"`  // 历史消息超过该 token 数时触发摘要
+	KeepTurns int    `yaml:"keep_turns"` // 最近的若干轮对话不参与摘要
+	Provider  string `yaml:"provider"`   // 生成摘要使用的服务，为空时使用默认服务
+	Model     string `yaml:"model"`      // 生成摘要使用的模型，可配置更便宜的模型
+}
+
+// ModelContextConfig 单个模型的上下文窗口配置
+type ModelContextConfig struct {
+	ContextWindow int `yaml:"context_window"`
+	ReserveOutput int `yaml:"reserve_output"`
+}
+
+// InstanceConfig 具名 AI 实例配置，type 决定使用的客户端实现
+type InstanceConfig struct {
+	Name           string   `yaml:"name"`
+	Type           string   `yaml:"type"` // openai、volc、anthropic、ollama、mock
+	Enable         bool     `yaml:"enable"`
+	APIKey         string   `yaml:"api_key"`
+	Model          string   `yaml:"model"`
+	APIURL         string   `yaml:"api_url"`
+	Models         []string `yaml:"models"`
+	Mode           string   `yaml:"mode"`            // 仅 volc 使用
+	ThinkingBudget int      `yaml:"thinking_budget"` // 仅 anthropic 使用
+	VisionModels   []string `yaml:"vision_models"`   // 支持图片输入的模型
+	EmbeddingModel string   `yaml:"embedding_model"` // 知识库使用的向量化模型
+	Fixture        string   `yaml:"fixture"`         // 仅 mock 使用
+	// Generation 生成参数，与服务配置写在同一层级
+	Generation GenerationConfig `yaml:",inline"`
+}
+
+// GenerationConfig 生成参数，未配置的参数不发送给模型服务，使用服务端的默认值
+// 各服务支持的参数不同，不支持的参数会被忽略
+type GenerationConfig struct {
+	Temperature     *float64 `yaml:"temperature"`
+	TopP            *float64 `yaml:"top_p"`
+	MaxTokens       int      `yaml:"max_tokens"` // 为 0 时使用默认值 8092
+	Stop            []string `yaml:"stop"`
+	PresencePenalty *float64 `yaml:"presence_penalty"`
+	Seed            *int     `yaml:"seed"`
+	ReasoningEffort string   `yaml:"reasoning_effort"` // minimal、low、medium、high
+}
+
+// ReasoningEfforts reasoning_effort 的可选值
+var ReasoningEfforts = []string{"minimal", "low", "medium", "high"}
+
+// MaxStopSequences stop 最多配置的数量
+const MaxStopSequences = 4
+
+// Merge 返回以 override 中已设置的参数覆盖后的结果，override 为空时返回自身
+func (g GenerationConfig) Merge(override *GenerationConfig) GenerationConfig {
+	if override == nil {
+		return g
+	}
+	if override.Temperature != nil {
+		g.Temperature = override.Temperature
+	}
+	if override.TopP != nil {
+		g.TopP = override.TopP
+	}
+	if override.MaxTokens > 0 {
+		g.MaxTokens = override.MaxTokens
+	}
+	if override.Stop != nil {
+		g.Stop = override.Stop
+	}
+	if override.PresencePenalty != nil {
+		g.PresencePenalty = override.PresencePenalty
+	}
+	if override.Seed != nil {
+		g.Seed = override.Seed
+	}
+	if override.ReasoningEffort != "" {
+		g.ReasoningEffort = override.ReasoningEffort
+	}
+	return g
+}
+
+// Validate 校验参数取值范围
+func (g GenerationConfig) Validate() error {
+	if g.Temperature != nil && (*g.Temperature < 0 || *g.Temperature > 2) {
+		return fmt.Errorf("temperature 取值范围为 0~2")
+	}
+	if g.TopP != nil && (*g.TopP < 0 || *g.TopP > 1) {
+		return fmt.Errorf("top_p 取值范围为 0~1")
+	}
+	if g.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens 需大于 0")
+	}
+	if len(g.Stop) > MaxStopSequences {
+		return fmt.Errorf("stop 最多 %d 个", MaxStopSequences)
+	}
+	if g.PresencePenalty != nil && (*g.PresencePenalty < -2 || *g.PresencePenalty > 2) {
+		return fmt.Errorf("presence_penalty 取值范围为 -2~2")
+	}
+	if g.ReasoningEffort != "" && !slices.Contains(ReasoningEfforts, g.ReasoningEffort) {
+		return fmt.Errorf("reasoning_effort 可选值为 %s", strings.Join(ReasoningEfforts, "、"))
+	}
+	return nil
+}
+
+// RouteRule 模型路由规则，已配置的匹配条件需全部满足
+type RouteRule struct {
+	Name     string   `yaml:"name"`
+	ChatIds  []string `yaml:"chat_ids"`
+	UserIds  []string `yaml:"user_ids"`
+	ChatType string   `yaml:"chat_type"` // group 或 personal
+	Prefix   string   `yaml:"prefix"`    // 消息前缀，命中后会从消息中去除
+	Provider string   `yaml:"provider"`  // 服务类型或具名实例名称
+	Model    string   `yaml:"model"`     // 为空时使用 provider 配置的默认模型
 }
 
 // OpenAIConfig OpenAI配置
 type OpenAIConfig struct {
-	Enable bool   `yaml:"enable"`
-	APIKey string `yaml:"api_key"`
-	Model  string `yaml:"model"`
-	APIURL string `yaml:"api_url"`
+	Enable         bool     `yaml:"enable"`
+	APIKey         string   `yaml:"api_key"`
+	Model          string   `yaml:"model"`
+	APIURL         string   `yaml:"api_url"`
+	Models         []string `yaml:"models"`          // 可通过 /model 切换的其它模型
+	VisionModels   []string `yaml:"vision_models"`   // 支持图片输入的模型
+	EmbeddingModel string   `yaml:"embedding_model"` // 知识库使用的向量化模型
+	// Generation 生成参数，与服务配置写在同一层级
+	Generation GenerationConfig `yaml:",inline"`
 }
 
+// 火山引擎调用模式
+const (
+	VolcModeBot   = "bot"   // Bot API，model 填写应用 ID（bot-xxx）
+	VolcModeModel = "model" // 推理接入点，model 填写模型 ID 或接入点 ID（ep-xxx）
+)
+
 // VolcConfig 火山引擎配置
 type VolcConfig struct {
-	Enable bool   `yaml:"enable"`
-	APIKey string `yaml:"api_key"`
-	Model  string `yaml:"model"`
-	APIURL string `yaml:"api_url"`
+	Enable         bool     `yaml:"enable"`
+	APIKey         string   `yaml:"api_key"`
+	Model          string   `yaml:"model"`
+	APIURL         string   `yaml:"api_url"`
+	Mode           string   `yaml:"mode"`            // bot 或 model，默认 bot
+	Models         []string `yaml:"models"`          // 可通过 /model 切换的其它模型
+	VisionModels   []string `yaml:"vision_models"`   // 支持图片输入的模型
+	EmbeddingModel string   `yaml:"embedding_model"` // 知识库使用的向量化模型
+	// Generation 生成参数，与服务配置写在同一层级
+	Generation GenerationConfig `yaml:",inline"`
+}
+
+// AnthropicConfig Anthropic配置
+type AnthropicConfig struct {
+	Enable         bool     `yaml:"enable"`
+	APIKey         string   `yaml:"api_key"`
+	Model          string   `yaml:"model"`
+	APIURL         string   `yaml:"api_url"`
+	ThinkingBudget int      `yaml:"thinking_budget"` // 思考 token 预算，0 表示关闭 extended thinking
+	Models         []string `yaml:"models"`          // 可通过 /model 切换的其它模型
+	VisionModels   []string `yaml:"vision_models"`   // 支持图片输入的模型
+	// Generation 生成参数，与服务配置写在同一层级
+	Generation GenerationConfig `yaml:",inline"`
+}
+
+// OllamaConfig Ollama 本地模型配置
+type OllamaConfig struct {
+	Enable         bool     `yaml:"enable"`
+	Model          string   `yaml:"model"`
+	APIURL         string   `yaml:"api_url"`
+	Models         []string `yaml:"models"`          // 可通过 /model 切换的其它模型
+	VisionModels   []string `yaml:"vision_models"`   // 支持图片输入的模型
+	EmbeddingModel string   `yaml:"embedding_model"` // 知识库使用的向量化模型
+	// Generation 生成参数，与服务配置写在同一层级
+	Generation GenerationConfig `yaml:",inline"`
+}
+
+// MockConfig 模拟服务配置，按脚本文件返回固定内容，用于本地开发和演示
+type MockConfig struct {
+	Enable       bool     `yaml:"enable"`
+	Model        string   `yaml:"model"`         // 为空时为 mock
+	Models       []string `yaml:"models"`        // 可通过 /model 切换的其它模型
+	VisionModels []string `yaml:"vision_models"` // 支持图片输入的模型
+	Fixture      string   `yaml:"fixture"`       // 脚本文件路径，YAML 或 JSON，为空时复述提问
 }
 
 // LoadConfig 从文件加载配置
@@ -151,6 +371,176 @@ func GetVolcConfig() *VolcConfig {
 	return cfg.AI.Volc
 }
 
+// GetAnthropicConfig 获取 Anthropic 配置
+func GetAnthropicConfig() *AnthropicConfig {
+	cfg := GetConfig()
+	if cfg.AI == nil || cfg.AI.Anthropic == nil {
+		return nil
+	}
+	return cfg.AI.Anthropic
+}
+
+// GetOllamaConfig 获取 Ollama 配置
+func GetOllamaConfig() *OllamaConfig {
+	cfg := GetConfig()
+	if cfg.AI == nil || cfg.AI.Ollama == nil {
+		return nil
+	}
+	return cfg.AI.Ollama
+}
+
+// GetMockConfig 获取模拟服务配置
+func GetMockConfig() *MockConfig {
+	cfg := GetConfig()
+	if cfg.AI == nil || cfg.AI.Mock == nil {
+		return nil
+	}
+	return cfg.AI.Mock
+}
+
+// GetAIFallback 获取 AI 服务故障转移顺序
+func GetAIFallback() []string {
+	cfg := GetConfig()
+	if cfg.AI == nil {
+		return nil
+	}
+	return cfg.AI.Fallback
+}
+
+// GetAIDefault 获取默认服务名称，未配置 default 时使用 fallback 的首个服务
+func GetAIDefault() string {
+	cfg := GetConfig()
+	if cfg.AI == nil {
+		return ""
+	}
+	if cfg.AI.Default != "" {
+		return cfg.AI.Default
+	}
+	if len(cfg.AI.Fallback) > 0 {
+		return cfg.AI.Fallback[0]
+	}
+	return ""
+}
+
+// GetAIRoutes 获取模型路由规则
+func GetAIRoutes() []*RouteRule {
+	cfg := GetConfig()
+	if cfg.AI == nil {
+		return nil
+	}
+	return cfg.AI.Routes
+}
+
+// GetAIInstances 获取具名 AI 实例配置
+func GetAIInstances() []*InstanceConfig {
+	cfg := GetConfig()
+	if cfg.AI == nil {
+		return nil
+	}
+	return cfg.AI.Instances
+}
+
+// GetToolsConfig 获取工具调用配置
+func GetToolsConfig() *ToolsConfig {
+	cfg := GetConfig()
+	return cfg.Tools
+}
+
+// GetKBConfig 获取知识库配置，未配置的字段使用默认值
+func GetKBConfig() *KBConfig {
+	cfg := KBConfig{}
+	if GetConfig().KB != nil {
+		cfg = *GetConfig().KB
+	}
+	if cfg.Path == "" {
+		cfg.Path = consts.DefaultKBPath
+	}
+	if cfg.TopK <= 0 {
+		cfg.TopK = consts.DefaultKBTopK
+	}
+	if cfg.ChunkTokens <= 0 {
+		cfg.ChunkTokens = consts.DefaultKBChunkTokens
+	}
+	return &cfg
+}
+
+func IsKBEnabled() bool {
+	cfg := GetConfig().KB
+	return cfg != nil && cfg.Enable
+}
+
+// GetSystemPrompt 获取配置中指定会话的默认 system prompt
+func GetSystemPrompt(chatId string, chatType string) string {
+	cfg := GetConfig().Prompt
+	if cfg == nil {
+		return ""
+	}
+	if prompt, ok := cfg.Chats[chatId]; ok {
+		return prompt
+	}
+	if chatType == consts.GroupChatType && cfg.Group != "" {
+		return cfg.Group
+	}
+	if chatType == consts.UserChatType && cfg.Personal != "" {
+		return cfg.Personal
+	}
+	return cfg.Default
+}
+
+// GetPersonaPath 获取 /persona 设置的人设保存文件
+func GetPersonaPath() string {
+	cfg := GetConfig().Prompt
+	if cfg == nil || cfg.Path == "" {
+		return consts.DefaultPersonaPath
+	}
+	return cfg.Path
+}
+
+// IsPromptAdmin 检查用户是否为配置中的人设管理员
+func IsPromptAdmin(userId string) bool {
+	cfg := GetConfig().Prompt
+	return cfg != nil && slices.Contains(cfg.Admins, userId)
+}
+
+// IsToolsEnabled 检查工具调用是否启用
+func IsToolsEnabled() bool {
+	cfg := GetToolsConfig()
+	return cfg != nil && cfg.Enable
+}
+
+// GetContextBudget 获取模型可用于输入的 token 预算，即上下文窗口减去输出预留
+func GetContextBudget(model string) int {
+	window, reserve := consts.DefaultContextWindow, consts.DefaultReserveOutput
+	cfg := GetConfig()
+	if cfg.AI != nil && cfg.AI.Context != nil {
+		ctxCfg := cfg.AI.Context
+		if ctxCfg.ContextWindow > 0 {
+			window = ctxCfg.ContextWindow
+		}
+		if ctxCfg.ReserveOutput > 0 {
+			reserve = ctxCfg.ReserveOutput
+		}
+		if modelCfg, ok := ctxCfg.Models[model]; ok && modelCfg != nil {
+			if modelCfg.ContextWindow > 0 {
+				window = modelCfg.ContextWindow
+			}
+			if modelCfg.ReserveOutput > 0 {
+				reserve = modelCfg.ReserveOutput
+			}
+		}
+	}
+	return max(window-reserve, consts.MinContextBudget)
+}
+
+// GetContextConfig 获取上下文窗口配置
+func GetContextConfig() *ContextConfig {
+	cfg := GetConfig()
+	if cfg.AI == nil || cfg.AI.Context == nil {
+		return &ContextConfig{}
+	}
+	return cfg.AI.Context
+}
+
 // IsFeishuEnabled 检查飞书是否启用
 func IsFeishuEnabled() bool {
 	cfg := GetFeishuConfig()
@@ -181,6 +571,24 @@ func IsVolcEnabled() bool {
 	return cfg != nil && cfg.Enable
 }
 
+// IsAnthropicEnabled 检查 Anthropic 是否启用
+func IsAnthropicEnabled() bool {
+	cfg := GetAnthropicConfig()
+	return cfg != nil && cfg.Enable
+}
+
+// IsOllamaEnabled 检查 Ollama 是否启用
+func IsOllamaEnabled() bool {
+	cfg := GetOllamaConfig()
+	return cfg != nil && cfg.Enable
+}
+
+// IsMockEnabled 检查模拟服务是否启用
+func IsMockEnabled() bool {
+	cfg := GetMockConfig()
+	return cfg != nil && cfg.Enable
+}
+
 // 获取配置文件路径
 func getConfigPath() string {
 	// 获取环境变量，默认为 dev
@@ -209,15 +617,197 @@ func validateConfig(cfg *Config) error {
 	// 检查机器人服务
 	hasBotEnabled := hasEnabledService(v.FieldByName("Bot"))
 	// 检查 AI 服务
-	hasAIEnabled := hasEnabledService(v.FieldByName("AI"))
+	hasAIEnabled := hasEnabledService(v.FieldByName("AI")) || hasEnabledInstance(cfg.AI)
 
 	if !hasBotEnabled || !hasAIEnabled {
 		return fmt.Errorf("配置无效: 必须至少启用一个机器人服务和一个 AI 服务")
 	}
+	if err := validateInstances(cfg.AI); err != nil {
+		return err
+	}
+	if err := validateRoutes(cfg.AI); err != nil {
+		return err
+	}
+	if err := validateVolcMode(cfg.AI); err != nil {
+		return err
+	}
 
+	if err := validateContext(cfg.AI); err != nil {
+		return err
+	}
+
+	return validateGeneration(cfg.AI)
+}
+
+// builtinProviders 内置服务的名称，具名实例不能与其重名
+var builtinProviders = []string{"openai", "volc", "anthropic", "ollama", "mock"}
+
+// validateInstances 检查具名实例的名称不为空且不重复，故障转移的服务均已启用且不重复，启用多个服务时需指定默认服务
+func validateInstances(cfg *AIConfig) error {
+	names := map[string]bool{}
+	for _, instance := range cfg.Instances {
+		if instance == nil {
+			continue
+		}
+		if instance.Name == "" {
+			return fmt.Errorf("配置无效: 具名实例需配置 name")
+		}
+		if slices.Contains(builtinProviders, instance.Name) {
+			return fmt.Errorf("配置无效: 具名实例 %s 与内置服务重名", instance.Name)
+		}
+		if names[instance.Name] {
+			return fmt.Errorf("配置无效: 具名实例 %s 重复", instance.Name)
+		}
+		names[instance.Name] = true
+	}
+
+	enabled := enabledServices(cfg)
+
+	fallback := map[string]bool{}
+	for _, name := range cfg.Fallback {
+		if !slices.Contains(enabled, name) {
+			return fmt.Errorf("配置无效: ai.fallback 中的服务 %s 未启用", name)
+		}
+		if fallback[name] {
+			return fmt.Errorf("配置无效: ai.fallback 中的服务 %s 重复", name)
+		}
+		fallback[name] = true
+	}
+
+	defaultName := cfg.Default
+	if defaultName == "" && len(cfg.Fallback) > 0 {
+		defaultName = cfg.Fallback[0]
+	}
+	if defaultName == "" {
+		if len(enabled) > 1 {
+			sort.Strings(enabled)
+			return fmt.Errorf("配置无效: 启用了多个 AI 服务（%s），需配置 ai.default 或 ai.fallback 指定默认服务", strings.Join(enabled, "、"))
+		}
+		return nil
+	}
+	if !slices.Contains(enabled, defaultName) {
+		return fmt.Errorf("配置无效: 默认服务 %s 未启用", defaultName)
+	}
+	if cfg.Default != "" && len(cfg.Fallback) > 0 && cfg.Fallback[0] != cfg.Default {
+		return fmt.Errorf("配置无效: ai.default 为 %s，与 ai.fallback 的首个服务 %s 不一致", cfg.Default, cfg.Fallback[0])
+	}
+	return nil
+}
+
+// enabledServices 获取已启用的内置服务和具名实例名称
+func enabledServices(cfg *AIConfig) []string {
+	var enabled []string
+	for name, enable := range map[string]bool{
+		"openai":    cfg.OpenAI != nil && cfg.OpenAI.Enable,
+		"volc":      cfg.Volc != nil && cfg.Volc.Enable,
+		"anthropic": cfg.Anthropic != nil && cfg.Anthropic.Enable,
+		"ollama":    cfg.Ollama != nil && cfg.Ollama.Enable,
+		"mock":      cfg.Mock != nil && cfg.Mock.Enable,
+	} {
+		if enable {
+			enabled = append(enabled, name)
+		}
+	}
+	for _, instance := range cfg.Instances {
+		if instance != nil && instance.Enable {
+			enabled = append(enabled, instance.Name)
+		}
+	}
+	return enabled
+}
+
+// validateRoutes 检查路由规则指定的服务已启用，未指定时使用默认服务
+func validateRoutes(cfg *AIConfig) error {
+	enabled := enabledServices(cfg)
+	for i, route := range cfg.Routes {
+		if route == nil || route.Provider == "" {
+			continue
+		}
+		if !slices.Contains(enabled, route.Provider) {
+			name := route.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			return fmt.Errorf("配置无效: 路由规则 %s 的服务 %s 未启用", name, route.Provider)
+		}
+	}
+	return nil
+}
+
+// truncateStrategies 支持的上下文裁剪策略，与 pkg 中的策略名称保持一致
+var truncateStrategies = []string{"sliding_window", "drop_oldest_pairs", "last_turns", "summarize"}
+
+// validateContext 检查上下文裁剪策略，未配置时为 sliding_window
+func validateContext(cfg *AIConfig) error {
+	if cfg.Context == nil || cfg.Context.Truncate == "" {
+		return nil
+	}
+	if !slices.Contains(truncateStrategies, cfg.Context.Truncate) {
+		return fmt.Errorf("配置无效: ai.context.truncate %s 不支持，可选值为 %s", cfg.Context.Truncate, strings.Join(truncateStrategies, "、"))
+	}
+	return nil
+}
+
+// validateVolcMode 检查火山引擎的调用模式，未配置时为 bot
+func validateVolcMode(cfg *AIConfig) error {
+	modes := map[string]string{}
+	if cfg.Volc != nil {
+		modes["volc"] = cfg.Volc.Mode
+	}
+	for _, instance := range cfg.Instances {
+		if instance != nil && instance.Type == "volc" {
+			modes[instance.Name] = instance.Mode
+		}
+	}
+	for name, mode := range modes {
+		if mode != "" && mode != VolcModeBot && mode != VolcModeModel {
+			return fmt.Errorf("配置无效: %s 的 mode %s 不支持，可选值为 %s、%s", name, mode, VolcModeBot, VolcModeModel)
+		}
+	}
 	return nil
 }
 
+// validateGeneration 检查各服务配置的生成参数
+func validateGeneration(cfg *AIConfig) error {
+	generations := map[string]GenerationConfig{}
+	if cfg.OpenAI != nil {
+		generations["openai"] = cfg.OpenAI.Generation
+	}
+	if cfg.Volc != nil {
+		generations["volc"] = cfg.Volc.Generation
+	}
+	if cfg.Anthropic != nil {
+		generations["anthropic"] = cfg.Anthropic.Generation
+	}
+	if cfg.Ollama != nil {
+		generations["ollama"] = cfg.Ollama.Generation
+	}
+	for _, instance := range cfg.Instances {
+		if instance != nil {
+			generations[instance.Name] = instance.Generation
+		}
+	}
+	for name, generation := range generations {
+		if err := generation.Validate(); err != nil {
+			return fmt.Errorf("配置无效: %s 的生成参数 %v", name, err)
+		}
+	}
+	return nil
+}
+
+// hasEnabledInstance 检查是否有启用的具名 AI 实例
+func hasEnabledInstance(cfg *AIConfig) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, instance := range cfg.Instances {
+		if instance != nil && instance.Enable {
+			return true
+		}
+	}
+	return false
+}
+
 // hasEnabledService 检查结构体中是否有启用的服务
 func hasEnabledService(v reflect.Value) bool {
 	if v.Kind() == reflect.Ptr {
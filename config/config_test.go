@@ -0,0 +1,242 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestGenerationConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  GenerationConfig
+		wantErr string
+	}{
+		{name: "empty", config: GenerationConfig{}},
+		{name: "boundaries", config: GenerationConfig{Temperature: floatPtr(2), TopP: floatPtr(0), PresencePenalty: floatPtr(-2), Stop: []string{"a", "b", "c", "d"}, ReasoningEffort: "minimal"}},
+		{name: "temperature too high", config: GenerationConfig{Temperature: floatPtr(2.1)}, wantErr: "temperature"},
+		{name: "temperature negative", config: GenerationConfig{Temperature: floatPtr(-0.1)}, wantErr: "temperature"},
+		{name: "top_p too high", config: GenerationConfig{TopP: floatPtr(1.5)}, wantErr: "top_p"},
+		{name: "max_tokens negative", config: GenerationConfig{MaxTokens: -1}, wantErr: "max_tokens"},
+		{name: "too many stop sequences", config: GenerationConfig{Stop: []string{"a", "b", "c", "d", "e"}}, wantErr: "stop"},
+		{name: "presence_penalty too low", config: GenerationConfig{PresencePenalty: floatPtr(-2.5)}, wantErr: "presence_penalty"},
+		{name: "unknown reasoning_effort", config: GenerationConfig{ReasoningEffort: "max"}, wantErr: "reasoning_effort"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerationConfigMerge(t *testing.T) {
+	base := GenerationConfig{Temperature: floatPtr(0.7), MaxTokens: 1024, Stop: []string{"###"}}
+	tests := []struct {
+		name     string
+		override *GenerationConfig
+		want     GenerationConfig
+	}{
+		{name: "nil override", override: nil, want: base},
+		{name: "empty override keeps base", override: &GenerationConfig{}, want: base},
+		{
+			name:     "override set fields",
+			override: &GenerationConfig{Temperature: floatPtr(0), ReasoningEffort: "low"},
+			want:     GenerationConfig{Temperature: floatPtr(0), MaxTokens: 1024, Stop: []string{"###"}, ReasoningEffort: "low"},
+		},
+		{
+			name:     "empty stop clears base stop",
+			override: &GenerationConfig{Stop: []string{}},
+			want:     GenerationConfig{Temperature: floatPtr(0.7), MaxTokens: 1024, Stop: []string{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.Merge(tt.override); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Merge() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateInstances(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AIConfig
+		wantErr string
+	}{
+		{name: "single builtin", config: AIConfig{Volc: &VolcConfig{Enable: true}}},
+		{name: "single instance", config: AIConfig{Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai", Enable: true}}}},
+		{
+			name:   "disabled services need no default",
+			config: AIConfig{Volc: &VolcConfig{Enable: true}, OpenAI: &OpenAIConfig{}, Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai"}}},
+		},
+		{
+			name:    "several services without default",
+			config:  AIConfig{Volc: &VolcConfig{Enable: true}, Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai", Enable: true}}},
+			wantErr: "需配置 ai.default 或 ai.fallback",
+		},
+		{
+			name:   "explicit default",
+			config: AIConfig{Default: "deepseek", Volc: &VolcConfig{Enable: true}, Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai", Enable: true}}},
+		},
+		{
+			name:   "fallback as default",
+			config: AIConfig{Fallback: []string{"volc", "deepseek"}, Volc: &VolcConfig{Enable: true}, Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai", Enable: true}}},
+		},
+		{
+			name:    "fallback service not enabled",
+			config:  AIConfig{Fallback: []string{"volc", "openai"}, Volc: &VolcConfig{Enable: true}, OpenAI: &OpenAIConfig{}},
+			wantErr: "ai.fallback 中的服务 openai 未启用",
+		},
+		{
+			name:    "unknown fallback service",
+			config:  AIConfig{Fallback: []string{"volc", "deepseek"}, Volc: &VolcConfig{Enable: true}},
+			wantErr: "ai.fallback 中的服务 deepseek 未启用",
+		},
+		{
+			name:    "duplicate fallback service",
+			config:  AIConfig{Fallback: []string{"volc", "deepseek", "volc"}, Volc: &VolcConfig{Enable: true}, Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai", Enable: true}}},
+			wantErr: "ai.fallback 中的服务 volc 重复",
+		},
+		{
+			name:    "default not enabled",
+			config:  AIConfig{Default: "openai", Volc: &VolcConfig{Enable: true}, OpenAI: &OpenAIConfig{}},
+			wantErr: "默认服务 openai 未启用",
+		},
+		{
+			name:    "default differs from fallback",
+			config:  AIConfig{Default: "volc", Fallback: []string{"deepseek"}, Volc: &VolcConfig{Enable: true}, Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai", Enable: true}}},
+			wantErr: "不一致",
+		},
+		{
+			name:    "duplicate instance names",
+			config:  AIConfig{Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai", Enable: true}, {Name: "deepseek", Type: "volc"}}},
+			wantErr: "具名实例 deepseek 重复",
+		},
+		{
+			name:    "instance named after builtin provider",
+			config:  AIConfig{Instances: []*InstanceConfig{{Name: "openai", Type: "openai", Enable: true}}},
+			wantErr: "与内置服务重名",
+		},
+		{
+			name:    "instance without name",
+			config:  AIConfig{Instances: []*InstanceConfig{{Type: "openai", Enable: true}}},
+			wantErr: "需配置 name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateInstances(&tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateInstances() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateInstances() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	instances := []*InstanceConfig{{Name: "deepseek", Type: "openai", Enable: true}, {Name: "qwen", Type: "openai"}}
+	tests := []struct {
+		name    string
+		routes  []*RouteRule
+		wantErr string
+	}{
+		{name: "builtin provider", routes: []*RouteRule{{Name: "a", Provider: "volc"}}},
+		{name: "instance provider", routes: []*RouteRule{{Name: "a", Provider: "deepseek"}}},
+		{name: "empty provider uses default", routes: []*RouteRule{{Name: "a", Model: "ep-xxxx"}}},
+		{name: "unknown provider", routes: []*RouteRule{{Name: "code", Provider: "gemini"}}, wantErr: "路由规则 code 的服务 gemini 未启用"},
+		{name: "disabled builtin provider", routes: []*RouteRule{{Name: "a", Provider: "volc"}, {Provider: "openai"}}, wantErr: "路由规则 #2 的服务 openai 未启用"},
+		{name: "disabled instance provider", routes: []*RouteRule{{Name: "a", Provider: "qwen"}}, wantErr: "路由规则 a 的服务 qwen 未启用"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &AIConfig{Volc: &VolcConfig{Enable: true}, OpenAI: &OpenAIConfig{}, Instances: instances, Routes: tt.routes}
+			err := validateRoutes(cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateRoutes() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateRoutes() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AIConfig
+		wantErr string
+	}{
+		{name: "no context", config: AIConfig{}},
+		{name: "default strategy", config: AIConfig{Context: &ContextConfig{ContextWindow: 8000}}},
+		{name: "summarize", config: AIConfig{Context: &ContextConfig{Truncate: "summarize"}}},
+		{name: "last_turns", config: AIConfig{Context: &ContextConfig{Truncate: "last_turns", KeepTurns: 3}}},
+		{name: "unknown strategy", config: AIConfig{Context: &ContextConfig{Truncate: "sliding-window"}}, wantErr: "truncate sliding-window 不支持，可选值为 sliding_window、drop_oldest_pairs、last_turns、summarize"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateContext(&tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateContext() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateContext() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateVolcMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  AIConfig
+		wantErr string
+	}{
+		{name: "default mode", config: AIConfig{Volc: &VolcConfig{Enable: true}}},
+		{name: "bot", config: AIConfig{Volc: &VolcConfig{Enable: true, Mode: VolcModeBot}}},
+		{name: "model", config: AIConfig{Instances: []*InstanceConfig{{Name: "doubao", Type: "volc", Mode: VolcModeModel}}}},
+		{name: "unknown mode", config: AIConfig{Volc: &VolcConfig{Enable: true, Mode: "endpoint"}}, wantErr: "volc 的 mode endpoint 不支持"},
+		{name: "unknown instance mode", config: AIConfig{Instances: []*InstanceConfig{{Name: "doubao", Type: "volc", Mode: "Model"}}}, wantErr: "doubao 的 mode Model 不支持"},
+		{name: "other types ignore mode", config: AIConfig{Instances: []*InstanceConfig{{Name: "deepseek", Type: "openai", Mode: "x"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateVolcMode(&tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateVolcMode() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("validateVolcMode() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -6,4 +6,25 @@ var (
 	BotDingtalk = "dingtalk"
 )
 
-var MaxContextLength = 8192
+var (
+	// DefaultContextWindow 未配置时默认的模型上下文窗口 token 数
+	DefaultContextWindow = 32768
+	// DefaultReserveOutput 未配置时默认为输出预留的 token 数
+	DefaultReserveOutput = 8192
+	// MinContextBudget 输入 token 预算的下限，避免配置错误导致上下文全部被丢弃
+	MinContextBudget = 1024
+	// MaxSessionMessages 单个会话缓存的最大消息数，仅用于限制内存占用
+	MaxSessionMessages = 200
+	// MaxSessionDocuments 单个会话保留的最大文件数
+	MaxSessionDocuments = 5
+	// MaxFileSize 允许读取的最大文件字节数
+	MaxFileSize = 20 << 20
+	// DefaultPersonaPath /persona 设置的人设默认保存文件
+	DefaultPersonaPath = "data/persona.json"
+	// DefaultKBPath 知识库默认存储目录
+	DefaultKBPath = "data/kb"
+	// DefaultKBTopK 知识库每次检索的默认分块数
+	DefaultKBTopK = 4
+	// DefaultKBChunkTokens 知识库默认分块 token 数
+	DefaultKBChunkTokens = 500
+)
@@ -22,6 +22,10 @@ const (
 type CardKind string
 
 const (
-	ClearCard CardKind = "clear"
-	HelpCard  CardKind = "help"
+	ClearCard      CardKind = "clear"
+	HelpCard       CardKind = "help"
+	ModelCard      CardKind = "model"
+	StopCard       CardKind = "stop"
+	RegenerateCard CardKind = "regenerate"
+	ContinueCard   CardKind = "continue"
 )
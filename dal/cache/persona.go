@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// PersonaCache 按群聊保存 /persona 设置的 system prompt，不过期，修改后写入文件，重启后恢复
+type PersonaCache struct {
+	path     string
+	mu       sync.RWMutex
+	personas map[string]string
+}
+
+var personaCache *PersonaCache
+
+func GetPersonaCache() *PersonaCache {
+	return personaCache
+}
+
+// NewPersonaCache 从 path 读取已保存的人设，文件不存在时从空开始
+func NewPersonaCache(path string) error {
+	personas := map[string]string{}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &personas); err != nil {
+			return fmt.Errorf("load persona %s error: %w", path, err)
+		}
+	}
+	personaCache = &PersonaCache{path: path, personas: personas}
+	return nil
+}
+
+func (p *PersonaCache) GetPersona(chatId string) string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.personas[chatId]
+}
+
+func (p *PersonaCache) SetPersona(chatId string, prompt string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.personas[chatId] = prompt
+	return p.save()
+}
+
+func (p *PersonaCache) Reset(chatId string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.personas, chatId)
+	return p.save()
+}
+
+// save 先写临时文件再替换，避免写入中断损坏数据，调用方需持有锁
+func (p *PersonaCache) save() error {
+	data, err := json.Marshal(p.personas)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p.path), 0o755); err != nil {
+		return err
+	}
+	tmp := p.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.path)
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestPersonaCachePersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "persona.json")
+	if err := NewPersonaCache(path); err != nil {
+		t.Fatalf("NewPersonaCache() error = %v", err)
+	}
+	if err := GetPersonaCache().SetPersona("oc_a", "你是助手"); err != nil {
+		t.Fatalf("SetPersona() error = %v", err)
+	}
+	if err := GetPersonaCache().SetPersona("oc_b", "你是 SRE"); err != nil {
+		t.Fatalf("SetPersona() error = %v", err)
+	}
+	if err := GetPersonaCache().Reset("oc_b"); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+
+	// 重新加载模拟重启
+	if err := NewPersonaCache(path); err != nil {
+		t.Fatalf("reload error = %v", err)
+	}
+	tests := []struct {
+		chatId string
+		want   string
+	}{
+		{chatId: "oc_a", want: "你是助手"},
+		{chatId: "oc_b", want: ""},
+		{chatId: "oc_c", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chatId, func(t *testing.T) {
+			if got := GetPersonaCache().GetPersona(tt.chatId); got != tt.want {
+				t.Errorf("GetPersona() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package cache
 
 import (
 	"ai-stream-bot/client/ai"
+	"ai-stream-bot/config"
 	"ai-stream-bot/consts"
-	"ai-stream-bot/pkg"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -11,8 +13,30 @@ import (
 
 type SessionCache struct {
 	cache *cache.Cache
+	// msgMu 保证会话消息的读取-修改-写入不被其它写入打断
+	msgMu sync.Mutex
 }
 
+// SessionModel 会话固定使用的服务提供商和模型
+type SessionModel struct {
+	Provider string
+	Model    string
+}
+
+// SessionDocument 会话中上传的文件，按分块保存提取的文本
+type SessionDocument struct {
+	Name   string
+	Pages  int
+	Chars  int
+	Chunks []string
+}
+
+const (
+	sessionModelKeyPrefix    = "model:"
+	sessionDocumentKeyPrefix = "doc:"
+	sessionParamsKeyPrefix   = "params:"
+)
+
 var sessionCache *SessionCache
 
 func GetSessionCache() *SessionCache {
@@ -32,21 +56,96 @@ func (s *SessionCache) GetMsg(sessionId string) []ai.AiMessage {
 }
 
 func (s *SessionCache) SetMsg(sessionId string, msgs []ai.AiMessage) {
-	// 限制上下文长度
-	if pkg.GetStrPoolTotalLength(msgs) > consts.MaxContextLength {
-		// 创建新的切片来存储单数位置的消息
-		newMsgs := make([]ai.AiMessage, 0)
-		for i := 0; i < len(msgs); i++ {
-			if i%2 == 0 { // 只保留索引为偶数的消息（对应1,3,5...位置）
-				newMsgs = append(newMsgs, msgs[i])
-			}
+	s.msgMu.Lock()
+	defer s.msgMu.Unlock()
+	s.setMsg(sessionId, msgs)
+}
+
+// UpdateMsg 基于当前消息计算新消息并写入，期间不会有其它写入，update 返回错误时不写入
+func (s *SessionCache) UpdateMsg(sessionId string, update func(current []ai.AiMessage) ([]ai.AiMessage, error)) error {
+	s.msgMu.Lock()
+	defer s.msgMu.Unlock()
+	msgs, err := update(s.GetMsg(sessionId))
+	if err != nil {
+		return err
+	}
+	s.setMsg(sessionId, msgs)
+	return nil
+}
+
+func (s *SessionCache) setMsg(sessionId string, msgs []ai.AiMessage) {
+	// 只限制内存占用，上下文长度在请求前按模型的 token 预算裁剪
+	// 开头的 system 消息（如对话摘要）始终保留，从其后最早的消息开始丢弃，保留部分对齐到 user 开头
+	if len(msgs) > consts.MaxSessionMessages {
+		systems := 0
+		for systems < len(msgs) && msgs[systems].Role == "system" {
+			systems++
+		}
+		start := len(msgs) - max(consts.MaxSessionMessages-systems, 0)
+		if i := slices.IndexFunc(msgs[start:], func(m ai.AiMessage) bool { return m.Role == "user" }); i > 0 {
+			start += i
 		}
-		msgs = newMsgs
+		msgs = append(msgs[:systems:systems], msgs[start:]...)
 	}
 
 	s.cache.Set(sessionId, msgs, 12*time.Hour)
 }
 
+func (s *SessionCache) GetModel(sessionId string) *SessionModel {
+	m, ok := s.cache.Get(sessionModelKeyPrefix + sessionId)
+	if !ok {
+		return nil
+	}
+	return m.(*SessionModel)
+}
+
+func (s *SessionCache) SetModel(sessionId string, m *SessionModel) {
+	s.cache.Set(sessionModelKeyPrefix+sessionId, m, 12*time.Hour)
+}
+
+func (s *SessionCache) GetDocuments(sessionId string) []*SessionDocument {
+	docs, ok := s.cache.Get(sessionDocumentKeyPrefix + sessionId)
+	if !ok {
+		return nil
+	}
+	return docs.([]*SessionDocument)
+}
+
+// AddDocument 添加文件到会话，同名文件会被替换，超出数量上限时丢弃最早的文件
+func (s *SessionCache) AddDocument(sessionId string, doc *SessionDocument) {
+	var docs []*SessionDocument
+	for _, d := range s.GetDocuments(sessionId) {
+		if d.Name != doc.Name {
+			docs = append(docs, d)
+		}
+	}
+	docs = append(docs, doc)
+	if len(docs) > consts.MaxSessionDocuments {
+		docs = docs[len(docs)-consts.MaxSessionDocuments:]
+	}
+	s.cache.Set(sessionDocumentKeyPrefix+sessionId, docs, 12*time.Hour)
+}
+
+// GetParams 获取 /params 设置的生成参数，未设置时返回 nil
+func (s *SessionCache) GetParams(sessionId string) *config.GenerationConfig {
+	p, ok := s.cache.Get(sessionParamsKeyPrefix + sessionId)
+	if !ok {
+		return nil
+	}
+	return p.(*config.GenerationConfig)
+}
+
+func (s *SessionCache) SetParams(sessionId string, params *config.GenerationConfig) {
+	s.cache.Set(sessionParamsKeyPrefix+sessionId, params, 12*time.Hour)
+}
+
+func (s *SessionCache) ResetParams(sessionId string) {
+	s.cache.Delete(sessionParamsKeyPrefix + sessionId)
+}
+
 func (s *SessionCache) Clear(sessionId string) {
 	s.cache.Delete(sessionId)
+	s.cache.Delete(sessionModelKeyPrefix + sessionId)
+	s.cache.Delete(sessionDocumentKeyPrefix + sessionId)
+	s.cache.Delete(sessionParamsKeyPrefix + sessionId)
 }
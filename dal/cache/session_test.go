@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/consts"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/patrickmn/go-cache"
+)
+
+func TestSetMsgKeepsLeadingSystem(t *testing.T) {
+	s := &SessionCache{cache: cache.New(time.Hour, time.Hour)}
+	msgs := []ai.AiMessage{{Role: "system", Content: "summary"}}
+	for i := 0; i < consts.MaxSessionMessages+10; i++ {
+		msgs = append(msgs, ai.AiMessage{Role: "user", Content: fmt.Sprint(i)})
+	}
+	s.SetMsg("s", msgs)
+
+	got := s.GetMsg("s")
+	if len(got) != consts.MaxSessionMessages {
+		t.Fatalf("len = %d, want %d", len(got), consts.MaxSessionMessages)
+	}
+	if got[0].Content != "summary" {
+		t.Errorf("first message = %+v, want summary", got[0])
+	}
+	if last := got[len(got)-1].Content; last != fmt.Sprint(consts.MaxSessionMessages+9) {
+		t.Errorf("last message = %s", last)
+	}
+	if got[1].Content != "11" {
+		t.Errorf("oldest kept message = %s, want 11", got[1].Content)
+	}
+}
+
+func TestSetMsgTrimsToUserBoundary(t *testing.T) {
+	s := &SessionCache{cache: cache.New(time.Hour, time.Hour)}
+	msgs := []ai.AiMessage{{Role: "system", Content: "summary"}}
+	for i := 0; i < consts.MaxSessionMessages; i++ {
+		msgs = append(msgs,
+			ai.AiMessage{Role: "user", Content: fmt.Sprint(i)},
+			ai.AiMessage{Role: "assistant", Content: fmt.Sprint(i)},
+		)
+	}
+	s.SetMsg("s", msgs)
+
+	got := s.GetMsg("s")
+	if len(got) > consts.MaxSessionMessages {
+		t.Fatalf("len = %d, want at most %d", len(got), consts.MaxSessionMessages)
+	}
+	if got[0].Content != "summary" {
+		t.Errorf("first message = %+v, want summary", got[0])
+	}
+	if got[1].Role != "user" {
+		t.Errorf("oldest kept message = %+v, want a user message", got[1])
+	}
+	if last := got[len(got)-1]; last.Role != "assistant" || last.Content != fmt.Sprint(consts.MaxSessionMessages-1) {
+		t.Errorf("last message = %+v", last)
+	}
+}
+
+func TestUpdateMsg(t *testing.T) {
+	s := &SessionCache{cache: cache.New(time.Hour, time.Hour)}
+	s.SetMsg("s", []ai.AiMessage{{Role: "user", Content: "a"}})
+
+	err := s.UpdateMsg("s", func(current []ai.AiMessage) ([]ai.AiMessage, error) {
+		return nil, errors.New("changed")
+	})
+	if err == nil || len(s.GetMsg("s")) != 1 {
+		t.Fatalf("failed update should keep messages, err = %v, msgs = %v", err, s.GetMsg("s"))
+	}
+
+	err = s.UpdateMsg("s", func(current []ai.AiMessage) ([]ai.AiMessage, error) {
+		return append(current, ai.AiMessage{Role: "assistant", Content: "b"}), nil
+	})
+	if err != nil || len(s.GetMsg("s")) != 2 {
+		t.Fatalf("update err = %v, msgs = %v", err, s.GetMsg("s"))
+	}
+}
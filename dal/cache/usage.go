@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"ai-stream-bot/client/ai"
+	"sync"
+)
+
+// UsageStat 汇总的 token 用量，模型服务返回的用量和本地估算的用量分开累计
+type UsageStat struct {
+	ai.Usage
+	Requests int
+	// EstimatedUsage 模型服务未返回用量时估算的用量，不计入 Usage
+	EstimatedUsage    ai.Usage
+	EstimatedRequests int
+}
+
+// UsageCache 按用户和群聊汇总 token 用量，仅保存在内存中
+type UsageCache struct {
+	users map[string]*UsageStat
+	chats map[string]*UsageStat
+	mu    sync.Mutex
+}
+
+var usageCache *UsageCache
+
+func GetUsageCache() *UsageCache {
+	return usageCache
+}
+
+func NewUsageCache() {
+	usageCache = &UsageCache{
+		users: make(map[string]*UsageStat),
+		chats: make(map[string]*UsageStat),
+	}
+}
+
+// Record 记录一次请求的用量，返回该用户和群聊累计后的用量
+func (c *UsageCache) Record(userId, chatId string, usage *ai.Usage) (UsageStat, UsageStat) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return addUsage(c.users, userId, usage), addUsage(c.chats, chatId, usage)
+}
+
+// GetUserUsage 获取用户累计的用量
+func (c *UsageCache) GetUserUsage(userId string) UsageStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stat, ok := c.users[userId]; ok {
+		return *stat
+	}
+	return UsageStat{}
+}
+
+// GetChatUsage 获取群聊累计的用量
+func (c *UsageCache) GetChatUsage(chatId string) UsageStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if stat, ok := c.chats[chatId]; ok {
+		return *stat
+	}
+	return UsageStat{}
+}
+
+func addUsage(stats map[string]*UsageStat, key string, usage *ai.Usage) UsageStat {
+	stat, ok := stats[key]
+	if !ok {
+		stat = &UsageStat{}
+		stats[key] = stat
+	}
+	if usage.Estimated {
+		stat.EstimatedUsage.Add(usage)
+		stat.EstimatedRequests++
+	} else {
+		stat.Add(usage)
+		stat.Requests++
+	}
+	return *stat
+}
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"ai-stream-bot/client/ai"
+	"testing"
+)
+
+func TestUsageCacheRecordSeparatesEstimated(t *testing.T) {
+	NewUsageCache()
+	c := GetUsageCache()
+	c.Record("u1", "c1", &ai.Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30})
+	c.Record("u1", "c1", &ai.Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7, Estimated: true})
+	userStat, chatStat := c.Record("u2", "c1", &ai.Usage{PromptTokens: 1, CompletionTokens: 1, TotalTokens: 2})
+
+	if got := c.GetUserUsage("u1"); got.TotalTokens != 30 || got.Requests != 1 || got.EstimatedUsage.TotalTokens != 7 || got.EstimatedRequests != 1 {
+		t.Errorf("u1 usage = %+v", got)
+	}
+	if userStat.TotalTokens != 2 || userStat.EstimatedRequests != 0 {
+		t.Errorf("u2 usage = %+v", userStat)
+	}
+	if chatStat.TotalTokens != 32 || chatStat.Requests != 2 || chatStat.EstimatedUsage.TotalTokens != 7 || chatStat.EstimatedRequests != 1 {
+		t.Errorf("c1 usage = %+v", chatStat)
+	}
+	if got := c.GetChatUsage("c2"); got.Requests != 0 || got.EstimatedRequests != 0 {
+		t.Errorf("c2 usage = %+v", got)
+	}
+}
@@ -0,0 +1,206 @@
+package kb
+
+import (
+	"ai-stream-bot/pkg/document"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Entry 知识库条目，一段文本或一个文件
+type Entry struct {
+	ID        string    `json:"id"`
+	Source    string    `json:"source"` // 文件名或文本摘要，检索结果中作为来源展示
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	Chunks    []*Chunk  `json:"chunks"`
+}
+
+// Chunk 条目的分块，配置了向量化服务时保存向量
+type Chunk struct {
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector,omitempty"`
+}
+
+// Hit 检索命中的分块
+type Hit struct {
+	EntryId string
+	Source  string
+	Text    string
+	Score   float64
+}
+
+// Store 按群聊隔离的知识库，每个群聊一个 JSON 文件，检索时全量计算相似度
+type Store struct {
+	dir   string
+	mu    sync.RWMutex
+	chats map[string][]*Entry
+}
+
+var store *Store
+
+func GetStore() *Store {
+	return store
+}
+
+func NewStore(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	store = &Store{dir: dir, chats: map[string][]*Entry{}}
+	return nil
+}
+
+// Add 添加条目并写入文件，返回条目 ID
+func (s *Store) Add(chatId string, entry *Entry) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load(chatId)
+	if err != nil {
+		return "", err
+	}
+	entry.ID = uuid.New().String()[:8]
+	entry.CreatedAt = time.Now()
+	entries = append(entries, entry)
+	if err := s.save(chatId, entries); err != nil {
+		return "", err
+	}
+	s.chats[chatId] = entries
+	return entry.ID, nil
+}
+
+// List 列出群聊的全部条目
+func (s *Store) List(chatId string) ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load(chatId)
+}
+
+// Delete 删除条目，条目不存在时返回 false
+func (s *Store) Delete(chatId string, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.load(chatId)
+	if err != nil {
+		return false, err
+	}
+	kept := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		if e.ID != id {
+			kept = append(kept, e)
+		}
+	}
+	if len(kept) == len(entries) {
+		return false, nil
+	}
+	if err := s.save(chatId, kept); err != nil {
+		return false, err
+	}
+	s.chats[chatId] = kept
+	return true, nil
+}
+
+// Search 检索与问题最相关的 topK 个分块
+// vector 不为空时按余弦相似度计算，缺少向量的分块按关键词命中计算
+func (s *Store) Search(chatId string, query string, vector []float32, topK int) ([]Hit, error) {
+	s.mu.Lock()
+	entries, err := s.load(chatId)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := document.Terms(query)
+	var hits []Hit
+	for _, e := range entries {
+		for _, c := range e.Chunks {
+			var score float64
+			if len(vector) > 0 && len(c.Vector) == len(vector) {
+				score = cosine(vector, c.Vector)
+			} else {
+				// 关键词得分归一化到 0~1，与向量得分大致可比
+				n := document.Score(c.Text, terms)
+				score = float64(n) / float64(n+len(terms))
+			}
+			if score > 0 {
+				hits = append(hits, Hit{EntryId: e.ID, Source: e.Source, Text: c.Text, Score: score})
+			}
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].Score > hits[j].Score
+	})
+	if len(hits) > topK {
+		hits = hits[:topK]
+	}
+	return hits, nil
+}
+
+// load 读取群聊的条目，调用方需持有锁
+func (s *Store) load(chatId string) ([]*Entry, error) {
+	if entries, ok := s.chats[chatId]; ok {
+		return entries, nil
+	}
+	data, err := os.ReadFile(s.path(chatId))
+	if errors.Is(err, os.ErrNotExist) {
+		s.chats[chatId] = nil
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("load kb %s error: %w", chatId, err)
+	}
+	s.chats[chatId] = entries
+	return entries, nil
+}
+
+// save 先写临时文件再替换，避免写入中断损坏数据
+func (s *Store) save(chatId string, entries []*Entry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	tmp := s.path(chatId) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(chatId))
+}
+
+// path 群聊对应的文件路径，chat_id 中只保留安全字符
+func (s *Store) path(chatId string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9') {
+			return r
+		}
+		return '_'
+	}, chatId)
+	return filepath.Join(s.dir, name+".json")
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,113 @@
+package kb
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := NewStore(dir); err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	return GetStore(), dir
+}
+
+func addEntry(t *testing.T, s *Store, chatId, source string, chunks ...*Chunk) string {
+	t.Helper()
+	id, err := s.Add(chatId, &Entry{Source: source, CreatedBy: "u1", Chunks: chunks})
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	return id
+}
+
+func TestStoreAddDeletePersist(t *testing.T) {
+	s, dir := newTestStore(t)
+	first := addEntry(t, s, "oc_a", "部署文档", &Chunk{Text: "服务部署流程"})
+	addEntry(t, s, "oc_a", "报销制度", &Chunk{Text: "报销流程说明"})
+	addEntry(t, s, "oc_b", "其它群", &Chunk{Text: "其它群的内容"})
+
+	if deleted, err := s.Delete("oc_a", "missing"); err != nil || deleted {
+		t.Errorf("Delete(missing) = %v, %v, want false", deleted, err)
+	}
+	if deleted, err := s.Delete("oc_a", first); err != nil || !deleted {
+		t.Errorf("Delete(%s) = %v, %v, want true", first, deleted, err)
+	}
+
+	// 重新创建模拟重启，条目从文件读取
+	if err := NewStore(dir); err != nil {
+		t.Fatalf("NewStore() error = %v", err)
+	}
+	tests := []struct {
+		chatId      string
+		wantSources []string
+	}{
+		{chatId: "oc_a", wantSources: []string{"报销制度"}},
+		{chatId: "oc_b", wantSources: []string{"其它群"}},
+		{chatId: "oc_c", wantSources: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chatId, func(t *testing.T) {
+			entries, err := GetStore().List(tt.chatId)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var sources []string
+			for _, e := range entries {
+				sources = append(sources, e.Source)
+			}
+			if !reflect.DeepEqual(sources, tt.wantSources) {
+				t.Errorf("sources = %v, want %v", sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestStorePath(t *testing.T) {
+	s, dir := newTestStore(t)
+	addEntry(t, s, "../oc/x", "文档", &Chunk{Text: "内容"})
+	if _, err := os.Stat(filepath.Join(dir, "___oc_x.json")); err != nil {
+		t.Errorf("chat_id should be sanitized into the store dir: %v", err)
+	}
+}
+
+func TestStoreSearch(t *testing.T) {
+	s, _ := newTestStore(t)
+	addEntry(t, s, "oc_a", "部署文档", &Chunk{Text: "服务部署流程：先构建镜像再发布"}, &Chunk{Text: "报销流程说明"})
+	addEntry(t, s, "oc_a", "向量文档", &Chunk{Text: "向量 A", Vector: []float32{1, 0}}, &Chunk{Text: "向量 B", Vector: []float32{0, 1}})
+
+	tests := []struct {
+		name     string
+		chatId   string
+		query    string
+		vector   []float32
+		topK     int
+		wantText []string
+	}{
+		{name: "keyword ranking", chatId: "oc_a", query: "部署流程", topK: 4, wantText: []string{"服务部署流程：先构建镜像再发布", "报销流程说明"}},
+		{name: "top k", chatId: "oc_a", query: "部署流程", topK: 1, wantText: []string{"服务部署流程：先构建镜像再发布"}},
+		{name: "vector", chatId: "oc_a", vector: []float32{1, 0.1}, topK: 4, wantText: []string{"向量 A", "向量 B"}},
+		{name: "vector dimension mismatch uses keywords", chatId: "oc_a", query: "报销", vector: []float32{1, 0, 0}, topK: 4, wantText: []string{"报销流程说明"}},
+		{name: "no hit", chatId: "oc_a", query: "天气", topK: 4, wantText: nil},
+		{name: "other chat", chatId: "oc_b", query: "部署流程", topK: 4, wantText: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits, err := s.Search(tt.chatId, tt.query, tt.vector, tt.topK)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			var texts []string
+			for _, h := range hits {
+				texts = append(texts, h.Text)
+			}
+			if !reflect.DeepEqual(texts, tt.wantText) {
+				t.Errorf("hits = %v, want %v", texts, tt.wantText)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ go 1.23.4
 require (
 	github.com/cloudwego/hertz v0.9.6
 	github.com/google/uuid v1.3.0
+	github.com/ledongthuc/pdf v0.0.0-20240201131950-da5b75280b06
 	github.com/patrickmn/go-cache v2.1.0+incompatible
 	github.com/volcengine/volcengine-go-sdk v1.0.184
 	gopkg.in/yaml.v3 v3.0.1
@@ -30,18 +30,30 @@ func (h *FeishuCardHandler) Handle(ctx context.Context, event *callback.CardActi
 	}
 	actionInfo.Ctx = ctx
 	actionInfo.SessionCache = cache.GetSessionCache()
+	if event.Event.Operator != nil && event.Event.Operator.UserID != nil {
+		actionInfo.UserId = *event.Event.Operator.UserID
+	}
 	actions := []model.CardAction{
 		&service.ClearCardService{},
+		&service.ModelCardService{},
+		&service.StopCardService{},
+		&service.ReplyCardService{},
 	}
 	card, ok := cardChain(&actionInfo, actions...)
-	if !ok {
+	if !ok || (card == nil && actionInfo.Toast == "") {
 		return nil, fmt.Errorf("card chain failed")
 	}
-	callbackCard := &callback.Card{
-		Type: "raw",
-		Data: card,
+	resp := &callback.CardActionTriggerResponse{}
+	if actionInfo.Toast != "" {
+		resp.Toast = &callback.Toast{Type: "info", Content: actionInfo.Toast}
+	}
+	if card != nil {
+		resp.Card = &callback.Card{
+			Type: "raw",
+			Data: card,
+		}
 	}
-	return &callback.CardActionTriggerResponse{Card: callbackCard}, nil
+	return resp, nil
 }
 
 // 责任链
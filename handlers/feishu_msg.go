@@ -54,19 +54,24 @@ func (h *FeishuMsgHandler) Handle(ctx context.Context, event *larkim.P2MessageRe
 		UserId:    *event.Event.Sender.SenderId.UserId,
 		ChatId:    chatId,
 		Content:   strings.Trim(parseContent(*msgContent, msgType), " "),
+		ImageKeys: parseImageKeys(*msgContent, msgType),
 		SessionId: sessionId,
 		Mention:   mention,
 	}
+	actionMsgInfo.FileKey, actionMsgInfo.FileName = parseFile(*msgContent, msgType)
 	data := &model.MsgActionInfo{
 		Ctx:           ctx,
 		ActionMsgInfo: &actionMsgInfo,
 		MsgCache:      cache.GetMsgCache(),
 		SessionCache:  cache.GetSessionCache(),
+		UsageCache:    cache.GetUsageCache(),
+		PersonaCache:  cache.GetPersonaCache(),
 		MessageEvent:  event,
 	}
 	actions := []model.MsgAction{
 		&service.ProcessedUniqueService{},            // 避免重复处理
 		&service.ProcessMentionService{},             // 判断机器人是否应该被调用
+		&service.FileService{},                       // 文件消息处理
 		&service.EmptyService{},                      // 空消息处理
 		&service.CommandService{},                    // 清除消息处理
 		service.NewFeishuMsgService(ai.GetManager()), // 消息处理
@@ -102,6 +107,46 @@ func parseContent(content string, msgType consts.MsgType) string {
 	return ""
 }
 
+func parseImageKeys(content string, msgType consts.MsgType) []string {
+	if msgType == consts.MsgTypeImage {
+		//"{\"image_key\":\"img_v3_xxx\"}"
+		var contentMap map[string]interface{}
+		err := json.Unmarshal([]byte(content), &contentMap)
+		if err != nil {
+			hlog.Errorf("error unmarshalling content: %v", err)
+			return nil
+		}
+		if imageKey, ok := contentMap["image_key"].(string); ok && imageKey != "" {
+			return []string{imageKey}
+		}
+	} else if msgType == consts.MsgTypePost {
+		keys, err := pkg.ExtractImageKeysFromFeishuMessage(content)
+		if err != nil {
+			hlog.Errorf("error extracting image keys from feishu message: %v", err)
+			return nil
+		}
+		return keys
+	}
+	return nil
+}
+
+func parseFile(content string, msgType consts.MsgType) (string, string) {
+	if msgType != consts.MsgTypeFile {
+		return "", ""
+	}
+	//"{\"file_key\":\"file_v3_xxx\",\"file_name\":\"a.pdf\"}"
+	var file struct {
+		FileKey  string `json:"file_key"`
+		FileName string `json:"file_name"`
+	}
+	err := json.Unmarshal([]byte(content), &file)
+	if err != nil {
+		hlog.Errorf("error unmarshalling content: %v", err)
+		return "", ""
+	}
+	return file.FileKey, file.FileName
+}
+
 func msgFilter(msg string) string {
 	//replace @到下一个非空的字段 为 ''
 	regex := regexp.MustCompile(`@[^ ]*`)
@@ -128,6 +173,10 @@ func judgeMsgType(event *larkim.P2MessageReceiveV1) (consts.MsgType, error) {
 		return consts.MsgTypeText, nil
 	case string(consts.MsgTypePost):
 		return consts.MsgTypePost, nil
+	case string(consts.MsgTypeImage):
+		return consts.MsgTypeImage, nil
+	case string(consts.MsgTypeFile):
+		return consts.MsgTypeFile, nil
 	default:
 		return "", fmt.Errorf("unknown message type: %v", *msgType)
 	}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"ai-stream-bot/dal/cache"
+	"ai-stream-bot/dal/kb"
 	"context"
 	"fmt"
 	"net/http"
@@ -54,12 +55,61 @@ func setupRouter(h *server.Hertz) {
 	if config.IsVolcEnabled() {
 		volcCfg := config.GetVolcConfig()
 		aiManager.RegisterClient(ai.NewVolcClient(volcCfg))
-		aiManager.SetDefaultClient(ai.ProviderVolc)
+		aiManager.SetDefaultClient(string(ai.ProviderVolc))
 	}
 	if config.IsOpenAIEnabled() {
 		openaiCfg := config.GetOpenAIConfig()
 		aiManager.RegisterClient(ai.NewOpenAIClient(openaiCfg))
-		aiManager.SetDefaultClient(ai.ProviderOpenAI)
+		aiManager.SetDefaultClient(string(ai.ProviderOpenAI))
+	}
+	if config.IsAnthropicEnabled() {
+		anthropicCfg := config.GetAnthropicConfig()
+		aiManager.RegisterClient(ai.NewAnthropicClient(anthropicCfg))
+		aiManager.SetDefaultClient(string(ai.ProviderAnthropic))
+	}
+	if config.IsOllamaEnabled() {
+		ollamaCfg := config.GetOllamaConfig()
+		aiManager.RegisterClient(ai.NewOllamaClient(ollamaCfg))
+		aiManager.SetDefaultClient(string(ai.ProviderOllama))
+	}
+	if config.IsMockEnabled() {
+		aiManager.RegisterClient(ai.NewMockClient(config.GetMockConfig()))
+		aiManager.SetDefaultClient(string(ai.ProviderMock))
+	}
+	// 具名实例
+	factory := ai.NewFactory()
+	for _, instanceCfg := range config.GetAIInstances() {
+		if !instanceCfg.Enable {
+			continue
+		}
+		client, err := factory.CreateClient(instanceCfg)
+		if err != nil {
+			hlog.Errorf("创建 AI 实例 %s 失败: %v", instanceCfg.Name, err)
+			continue
+		}
+		aiManager.RegisterNamedClient(instanceCfg.Name, client)
+		aiManager.SetDefaultClient(instanceCfg.Name)
+	}
+	if name := config.GetAIDefault(); name != "" {
+		if err := aiManager.SetDefaultClient(name); err != nil {
+			hlog.Errorf("设置默认 AI 服务失败: %v", err)
+			os.Exit(1)
+		}
+	}
+	// 注册工具
+	if config.IsToolsEnabled() {
+		toolRegistry := ai.GetToolRegistry()
+		toolRegistry.Register(ai.NewCurrentTimeTool())
+		toolRegistry.Register(ai.NewCalculatorTool())
+		if allowlist := config.GetToolsConfig().HTTPAllowlist; len(allowlist) > 0 {
+			toolRegistry.Register(ai.NewHTTPGetTool(allowlist))
+		}
+	}
+	if fallback := config.GetAIFallback(); len(fallback) > 0 {
+		if err := aiManager.SetFallbackChain(fallback...); err != nil {
+			hlog.Errorf("设置 AI 故障转移顺序失败: %v", err)
+			os.Exit(1)
+		}
 	}
 }
 
@@ -97,6 +147,18 @@ func main() {
 	// 初始化 cache
 	cache.NewMsgCache()
 	cache.NewSessionCache()
+	cache.NewUsageCache()
+	if err := cache.NewPersonaCache(config.GetPersonaPath()); err != nil {
+		hlog.Errorf("初始化人设失败: %v", err)
+		os.Exit(1)
+	}
+	// 初始化知识库
+	if config.IsKBEnabled() {
+		if err := kb.NewStore(config.GetKBConfig().Path); err != nil {
+			hlog.Errorf("初始化知识库失败: %v", err)
+			os.Exit(1)
+		}
+	}
 
 	// 创建 Hertz 实例
 	h := server.Default(
@@ -16,6 +16,9 @@ type ActionMsgInfo struct {
 	ChatId    *string
 	UserId    string
 	Content   string
+	ImageKeys []string // 图片消息及富文本消息中的图片
+	FileKey   string   // 文件消息的文件 key
+	FileName  string
 	SessionId *string
 	Mention   []*larkim.MentionEvent
 }
@@ -25,6 +28,8 @@ type MsgActionInfo struct {
 	ActionMsgInfo *ActionMsgInfo
 	MsgCache      *cache.MsgCache
 	SessionCache  *cache.SessionCache
+	UsageCache    *cache.UsageCache
+	PersonaCache  *cache.PersonaCache
 	MessageEvent  *larkim.P2MessageReceiveV1
 }
 
@@ -35,7 +40,15 @@ type CardActionInfo struct {
 	Value        interface{}     `json:"value"`
 	SessionId    string          `json:"sessionId"`
 	MsgId        string          `json:"msgId"`
+	CardId       string          `json:"cardId"`
+	ChatId       string          `json:"chatId"`
+	UserId       string          `json:"-"` // 点击按钮的用户
 	SessionCache *cache.SessionCache
+	// Toast 回调响应中展示的提示，不需要更新卡片时使用
+	Toast string `json:"-"`
+	// Turn、Digest 回答按钮对应的会话消息下标和回答内容摘要，用于确认仍是最新的回答
+	Turn   int    `json:"turn"`
+	Digest string `json:"digest"`
 }
 
 type MsgAction interface {
@@ -48,6 +61,7 @@ type CardAction interface {
 
 type StreamUpdateMessage struct {
 	Thinking  string
+	Tool      string
 	Reference string
 	Answer    string
 }
@@ -0,0 +1,168 @@
+package document
+
+import (
+	"ai-stream-bot/pkg"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultChunkTokens 单个分块的默认 token 数
+const DefaultChunkTokens = 800
+
+// Chunk 按段落将文本切分为不超过 maxTokens 的分块，超长段落按行和字符继续切分
+func Chunk(text string, maxTokens int) []string {
+	if maxTokens <= 0 {
+		maxTokens = DefaultChunkTokens
+	}
+	var chunks []string
+	var current strings.Builder
+	currentTokens := 0
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+		currentTokens = 0
+	}
+	for _, piece := range splitPieces(text, maxTokens) {
+		tokens := pkg.EstimateTokens(piece)
+		if currentTokens+tokens > maxTokens {
+			flush()
+		}
+		current.WriteString(piece)
+		current.WriteString("\n")
+		currentTokens += tokens
+	}
+	flush()
+	return chunks
+}
+
+// splitPieces 将文本拆为不超过 maxTokens 的段落或片段
+func splitPieces(text string, maxTokens int) []string {
+	var pieces []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if pkg.EstimateTokens(line) <= maxTokens {
+			pieces = append(pieces, line)
+			continue
+		}
+		// 没有换行的超长文本按字符切分
+		var part strings.Builder
+		partTokens := 0
+		for _, r := range line {
+			tokens := pkg.EstimateTokens(string(r))
+			if partTokens+tokens > maxTokens {
+				pieces = append(pieces, part.String())
+				part.Reset()
+				partTokens = 0
+			}
+			part.WriteRune(r)
+			partTokens += tokens
+		}
+		if part.Len() > 0 {
+			pieces = append(pieces, part.String())
+		}
+	}
+	return pieces
+}
+
+// Select 在 budget 内选取与 query 最相关的分块，按原文顺序返回
+// 全部分块未超出预算时直接返回；相关度按关键词命中次数计算，均未命中时从头选取
+func Select(chunks []string, query string, budget int) []string {
+	total := 0
+	for _, c := range chunks {
+		total += pkg.EstimateTokens(c)
+	}
+	if total <= budget {
+		return chunks
+	}
+
+	terms := Terms(query)
+	type scored struct {
+		index int
+		score int
+	}
+	ranked := make([]scored, len(chunks))
+	for i, c := range chunks {
+		ranked[i] = scored{index: i, score: Score(c, terms)}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].score > ranked[j].score
+	})
+
+	selected := make([]bool, len(chunks))
+	used := 0
+	for _, r := range ranked {
+		tokens := pkg.EstimateTokens(chunks[r.index])
+		if used+tokens > budget {
+			continue
+		}
+		selected[r.index] = true
+		used += tokens
+	}
+	var result []string
+	for i, c := range chunks {
+		if selected[i] {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// Terms 提取检索关键词：英文按单词，中日韩文字按相邻两字
+func Terms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	add := func(term string) {
+		if term != "" && !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	var word []rune
+	var cjk []rune
+	flush := func() {
+		if len(word) >= 2 {
+			add(strings.ToLower(string(word)))
+		}
+		word = word[:0]
+		if len(cjk) == 1 {
+			add(string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			add(string(cjk[i : i+2]))
+		}
+		cjk = cjk[:0]
+	}
+	for _, r := range query {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if len(word) > 0 {
+				flush()
+			}
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(cjk) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// Score 计算文本对关键词的命中次数
+func Score(text string, terms []string) int {
+	text = strings.ToLower(text)
+	score := 0
+	for _, term := range terms {
+		score += strings.Count(text, term)
+	}
+	return score
+}
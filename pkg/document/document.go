@@ -0,0 +1,202 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ledongthuc/pdf"
+)
+
+var (
+	// ErrUnsupported 不支持的文件类型
+	ErrUnsupported = errors.New("unsupported file type")
+	// ErrTooLarge 解压后的内容或提取的文本超过上限
+	ErrTooLarge = errors.New("document content too large")
+)
+
+const (
+	// maxZipEntrySize 从 docx 中解压读取的单个文件字节数上限，避免压缩炸弹
+	maxZipEntrySize = 64 << 20
+	// maxTextSize 提取的文本字节数上限
+	maxTextSize = 8 << 20
+)
+
+// Document 从文件中提取的文本
+type Document struct {
+	Name string
+	// Pages 页数，无法获取时为 0
+	Pages int
+	Text  string
+}
+
+// Chars 文本的字符数
+func (d *Document) Chars() int {
+	return utf8.RuneCountInString(d.Text)
+}
+
+// Parse 按扩展名解析文件，支持 pdf、docx、txt 和 markdown
+func Parse(name string, data []byte) (*Document, error) {
+	var (
+		doc *Document
+		err error
+	)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".pdf":
+		doc, err = parsePDF(data)
+	case ".docx":
+		doc, err = parseDOCX(data)
+	case ".txt", ".md", ".markdown":
+		doc = &Document{Text: strings.ToValidUTF8(string(data), "")}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	doc.Name = name
+	doc.Text = strings.TrimSpace(strings.ReplaceAll(doc.Text, "\r\n", "\n"))
+	return doc, nil
+}
+
+// parsePDF 逐页提取 PDF 文本，扫描件等没有文本层的页面会被跳过
+func parsePDF(data []byte) (doc *Document, err error) {
+	// 解析库遇到损坏的文件可能 panic
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("parse pdf panic: %v", r)
+		}
+	}()
+	reader, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	doc = &Document{Pages: reader.NumPage()}
+	var text strings.Builder
+	for i := 1; i <= doc.Pages; i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		content, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("parse pdf page %d error: %w", i, err)
+		}
+		text.WriteString(content)
+		text.WriteString("\n\n")
+		if text.Len() > maxTextSize {
+			return nil, ErrTooLarge
+		}
+	}
+	doc.Text = text.String()
+	return doc, nil
+}
+
+// parseDOCX 读取 word/document.xml 中的段落文本，页数取自 docProps/app.xml
+func parseDOCX(data []byte) (*Document, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	doc := &Document{}
+	for _, f := range reader.File {
+		switch f.Name {
+		case "word/document.xml":
+			if doc.Text, err = readZipFile(f, docxText); err != nil {
+				return nil, err
+			}
+		case "docProps/app.xml":
+			// 页数由 Word 保存时写入，缺失时忽略
+			pages, _ := readZipFile(f, docxPages)
+			doc.Pages, _ = strconv.Atoi(pages)
+		}
+	}
+	return doc, nil
+}
+
+// readZipFile 读取压缩包中的文件，解压后超过 maxZipEntrySize 时返回 ErrTooLarge
+func readZipFile(f *zip.File, read func(io.Reader) (string, error)) (string, error) {
+	// 文件头中的大小可以伪造，读取时仍需限制
+	if f.UncompressedSize64 > maxZipEntrySize {
+		return "", ErrTooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return read(&cappedReader{r: rc, remaining: maxZipEntrySize})
+}
+
+// cappedReader 读取超过 remaining 字节时返回 ErrTooLarge
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
+
+// docxText 提取 w:t 文本，段落和换行转换为换行符
+func docxText(r io.Reader) (string, error) {
+	var text strings.Builder
+	decoder := xml.NewDecoder(r)
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteString("\t")
+			case "br":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(t)
+			}
+		}
+		if text.Len() > maxTextSize {
+			return "", ErrTooLarge
+		}
+	}
+}
+
+func docxPages(r io.Reader) (string, error) {
+	var props struct {
+		Pages string `xml:"Pages"`
+	}
+	if err := xml.NewDecoder(r).Decode(&props); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(props.Pages), nil
+}
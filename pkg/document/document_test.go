@@ -0,0 +1,83 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// buildDOCX 生成只包含指定 word/document.xml 的 docx
+func buildDOCX(t *testing.T, documentXML string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte(documentXML)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func wordXML(body string) string {
+	return `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + body + `</w:body></w:document>`
+}
+
+func TestParseDOCX(t *testing.T) {
+	tests := []struct {
+		name     string
+		xml      string
+		wantText string
+		wantErr  error
+	}{
+		{
+			name:     "paragraphs and breaks",
+			xml:      wordXML(`<w:p><w:r><w:t>第一段</w:t></w:r></w:p><w:p><w:r><w:t>第二</w:t><w:br/><w:t>段</w:t></w:r></w:p>`),
+			wantText: "第一段\n第二\n段",
+		},
+		{
+			name:    "decompressed entry over limit",
+			xml:     wordXML(strings.Repeat(" ", maxZipEntrySize)),
+			wantErr: ErrTooLarge,
+		},
+		{
+			name:    "extracted text over limit",
+			xml:     wordXML(`<w:p><w:r><w:t>` + strings.Repeat("a", maxTextSize+1) + `</w:t></w:r></w:p>`),
+			wantErr: ErrTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse("a.docx", buildDOCX(t, tt.xml))
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Parse() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if doc.Text != tt.wantText {
+				t.Errorf("Text = %q, want %q", doc.Text, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestCappedReader(t *testing.T) {
+	data := strings.Repeat("x", 10)
+	if _, err := (&bytes.Buffer{}).ReadFrom(&cappedReader{r: strings.NewReader(data), remaining: 10}); err != nil {
+		t.Errorf("read at limit error = %v", err)
+	}
+	if _, err := (&bytes.Buffer{}).ReadFrom(&cappedReader{r: strings.NewReader(data), remaining: 9}); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("read over limit error = %v, want ErrTooLarge", err)
+	}
+}
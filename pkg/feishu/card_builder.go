@@ -84,3 +84,34 @@ func BuildEmbedButton(content string, value map[string]interface{},
 		Type(typename).
 		Build()
 }
+
+// BuildStreamCardButton 构建流式卡片（卡片 2.0）中的回调按钮
+func BuildStreamCardButton(elementId, text, btnType string, value map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"tag":        "button",
+		"element_id": elementId,
+		"text":       map[string]interface{}{"tag": "plain_text", "content": text},
+		"type":       btnType,
+		"size":       "small",
+		"behaviors":  []map[string]interface{}{{"type": "callback", "value": value}},
+	}
+}
+
+// BuildStreamCardButtonRow 将多个按钮横向排列
+func BuildStreamCardButtonRow(elementId string, buttons ...map[string]interface{}) map[string]interface{} {
+	columns := make([]map[string]interface{}, len(buttons))
+	for i, btn := range buttons {
+		columns[i] = map[string]interface{}{
+			"tag":      "column",
+			"width":    "auto",
+			"elements": []map[string]interface{}{btn},
+		}
+	}
+	return map[string]interface{}{
+		"tag":                "column_set",
+		"element_id":         elementId,
+		"flex_mode":          "none",
+		"horizontal_spacing": "8px",
+		"columns":            columns,
+	}
+}
@@ -98,3 +98,28 @@ func ExtractTextFromFeishuMessage(jsonStr string) (string, error) {
 
 	return strings.TrimSpace(result.String()), nil
 }
+
+// ExtractImageKeysFromFeishuMessage 提取飞书富文本消息中的图片 key
+func ExtractImageKeysFromFeishuMessage(jsonStr string) ([]string, error) {
+	var data struct {
+		Content [][]struct {
+			Tag      string `json:"tag"`
+			ImageKey string `json:"image_key"`
+		} `json:"content"`
+	}
+
+	err := json.Unmarshal([]byte(jsonStr), &data)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, paragraph := range data.Content {
+		for _, element := range paragraph {
+			if element.Tag == "img" && element.ImageKey != "" {
+				keys = append(keys, element.ImageKey)
+			}
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"ai-stream-bot/client/ai"
+	"unicode"
+)
+
+const (
+	// 每条消息的格式开销（role、分隔符等）
+	tokensPerMessage = 4
+	// 回复前缀的固定开销
+	tokensPerReply = 3
+	// 连续的 ASCII 字母数字平均每 4 个字符一个 token
+	asciiCharsPerToken = 4
+	// 图片按固定开销估算，主流视觉模型单张图片约数百到一千多 token
+	tokensPerImage = 1000
+)
+
+// EstimateTokens 估算文本的 token 数
+// 基于常见 BPE 分词器（cl100k、DeepSeek、Qwen）的经验值：中日韩文字约 1 字 1 token，
+// 英文单词约 4 字符 1 token，标点 1 token，其它字符（emoji 等）按 2 token 计，整体偏保守
+func EstimateTokens(s string) int {
+	tokens := 0
+	asciiRun := 0
+	flush := func() {
+		tokens += (asciiRun + asciiCharsPerToken - 1) / asciiCharsPerToken
+		asciiRun = 0
+	}
+	for _, r := range s {
+		switch {
+		case r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			asciiRun++
+		case unicode.IsSpace(r):
+			flush()
+		case r <= unicode.MaxASCII:
+			flush()
+			tokens++
+		case isCJK(r):
+			flush()
+			tokens++
+		default:
+			flush()
+			tokens += 2
+		}
+	}
+	flush()
+	return tokens
+}
+
+// EstimateMessagesTokens 估算消息列表作为请求输入时的 token 数
+func EstimateMessagesTokens(msgs []ai.AiMessage) int {
+	total := tokensPerReply
+	for _, m := range msgs {
+		total += EstimateMessageTokens(m)
+	}
+	return total
+}
+
+// EstimateMessageTokens 估算单条消息的 token 数
+func EstimateMessageTokens(m ai.AiMessage) int {
+	total := tokensPerMessage + EstimateTokens(m.Content)
+	total += len(m.Images()) * tokensPerImage
+	for _, call := range m.ToolCalls {
+		total += EstimateTokens(call.Function.Name) + EstimateTokens(call.Function.Arguments)
+	}
+	return total
+}
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) ||
+		unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) ||
+		unicode.Is(unicode.Hangul, r) ||
+		(r >= 0x3000 && r <= 0x303F) || // 中日韩标点
+		(r >= 0xFF00 && r <= 0xFFEF) // 全角字符
+}
@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"ai-stream-bot/client/ai"
+	"testing"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "short word", text: "hi", want: 1},
+		{name: "word rounds up", text: "hello", want: 2},
+		{name: "words", text: "hello world", want: 4},
+		{name: "digits", text: "12345678", want: 2},
+		{name: "ascii punctuation", text: "a,b", want: 3},
+		{name: "chinese", text: "你好世界", want: 4},
+		{name: "full width punctuation", text: "你好，世界。", want: 6},
+		{name: "japanese", text: "こんにちは", want: 5},
+		{name: "korean", text: "안녕", want: 2},
+		{name: "mixed", text: "Go语言", want: 3},
+		{name: "mixed with space", text: "你好 hello!", want: 5},
+		{name: "emoji", text: "😀", want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.want {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateMessageTokens(t *testing.T) {
+	image := ai.AiMessage{Role: "user", Content: "看图", Parts: []ai.ContentPart{
+		{Type: ai.ContentPartText, Text: "看图"},
+		{Type: ai.ContentPartImage, ImageURL: &ai.ImageURL{URL: "https://example.com/a.png"}},
+	}}
+	toolCall := ai.AiMessage{Role: "assistant", ToolCalls: []ai.ToolCall{
+		{ID: "1", Type: "function", Function: ai.ToolCallFunction{Name: "calculator", Arguments: "{}"}},
+	}}
+	tests := []struct {
+		name string
+		msg  ai.AiMessage
+		want int
+	}{
+		{name: "text", msg: user("你好"), want: tokensPerMessage + 2},
+		{name: "image", msg: image, want: tokensPerMessage + 2 + tokensPerImage},
+		{name: "tool call", msg: toolCall, want: tokensPerMessage + 3 + 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateMessageTokens(tt.msg); got != tt.want {
+				t.Errorf("EstimateMessageTokens() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	msgs := []ai.AiMessage{user("你好"), assistant("hello")}
+	if got, want := EstimateMessagesTokens(msgs), tokensPerReply+2*tokensPerMessage+2+2; got != want {
+		t.Errorf("EstimateMessagesTokens() = %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+package pkg
+
+import (
+	"ai-stream-bot/client/ai"
+)
+
+// 历史消息裁剪策略
+const (
+	TruncateSlidingWindow   = "sliding_window"    // 按 token 预算保留最近的消息
+	TruncateDropOldestPairs = "drop_oldest_pairs" // 按轮次从最早的对话开始丢弃，直到满足 token 预算
+	TruncateLastTurns       = "last_turns"        // 保留 system 消息和最近 N 轮对话
+	TruncateSummarize       = "summarize"         // 超过阈值后将较早的对话压缩为摘要，请求前仍按 sliding_window 兜底
+)
+
+// DefaultKeepTurns last_turns 策略默认保留的轮数
+const DefaultKeepTurns = 10
+
+// TruncateStrategy 历史消息裁剪策略
+// 所有策略都会保留 system 消息并置于最前，且保证之后的对话以 user 开头、user 与 assistant 交替
+type TruncateStrategy interface {
+	Truncate(msgs []ai.AiMessage, budget int) []ai.AiMessage
+}
+
+// NewTruncateStrategy 根据名称创建裁剪策略，未知名称和 summarize 使用 sliding_window
+func NewTruncateStrategy(name string, keepTurns int) TruncateStrategy {
+	switch name {
+	case TruncateDropOldestPairs:
+		return &DropOldestPairsStrategy{}
+	case TruncateLastTurns:
+		if keepTurns <= 0 {
+			keepTurns = DefaultKeepTurns
+		}
+		return &LastTurnsStrategy{Turns: keepTurns}
+	default:
+		return &SlidingWindowStrategy{}
+	}
+}
+
+// SlidingWindowStrategy 从最新的消息向前保留，直到用完 token 预算，最后一轮对话始终保留
+type SlidingWindowStrategy struct {
+}
+
+func (s *SlidingWindowStrategy) Truncate(msgs []ai.AiMessage, budget int) []ai.AiMessage {
+	systems, turns := splitTurns(msgs)
+	if len(turns) == 0 {
+		return systems
+	}
+
+	last := turns[len(turns)-1]
+	used := EstimateMessagesTokens(systems) + EstimateMessagesTokens(last) - tokensPerReply
+	// 在最后一轮之前按消息粒度向前填充，再对齐到 user 开头
+	history := joinTurns(turns[:len(turns)-1])
+	start := len(history)
+	for start > 0 {
+		cost := EstimateMessageTokens(history[start-1])
+		if used+cost > budget {
+			break
+		}
+		used += cost
+		start--
+	}
+	kept := append(normalizeDialog(history[start:]), last...)
+	return append(systems, kept...)
+}
+
+// DropOldestPairsStrategy 以轮为单位从最早的对话开始丢弃，直到满足 token 预算，最后一轮对话始终保留
+type DropOldestPairsStrategy struct {
+}
+
+func (s *DropOldestPairsStrategy) Truncate(msgs []ai.AiMessage, budget int) []ai.AiMessage {
+	systems, turns := splitTurns(msgs)
+	total := EstimateMessagesTokens(systems) - tokensPerReply
+	for _, turn := range turns {
+		total += EstimateMessagesTokens(turn) - tokensPerReply
+	}
+	for len(turns) > 1 && total+tokensPerReply > budget {
+		total -= EstimateMessagesTokens(turns[0]) - tokensPerReply
+		turns = turns[1:]
+	}
+	return append(systems, joinTurns(turns)...)
+}
+
+// LastTurnsStrategy 保留 system 消息和最近 Turns 轮对话，不考虑 token 预算
+type LastTurnsStrategy struct {
+	Turns int
+}
+
+func (s *LastTurnsStrategy) Truncate(msgs []ai.AiMessage, budget int) []ai.AiMessage {
+	systems, turns := splitTurns(msgs)
+	if len(turns) > s.Turns {
+		turns = turns[len(turns)-s.Turns:]
+	}
+	return append(systems, joinTurns(turns)...)
+}
+
+// SplitTurns 拆分出 system 消息和按轮分组的对话，规则同 splitTurns
+func SplitTurns(msgs []ai.AiMessage) ([]ai.AiMessage, [][]ai.AiMessage) {
+	return splitTurns(msgs)
+}
+
+// splitTurns 拆分出 system 消息，其余消息按轮分组，每轮以 user 开头
+// 开头缺少 user 的消息会被丢弃，连续的 user 消息只保留最后一条
+func splitTurns(msgs []ai.AiMessage) ([]ai.AiMessage, [][]ai.AiMessage) {
+	var systems []ai.AiMessage
+	var turns [][]ai.AiMessage
+	for _, m := range msgs {
+		switch {
+		case m.Role == "system":
+			systems = append(systems, m)
+		case m.Role == "user":
+			// 上一轮没有回复（如请求失败），用新的提问替换
+			if len(turns) > 0 && len(turns[len(turns)-1]) == 1 {
+				turns[len(turns)-1] = []ai.AiMessage{m}
+			} else {
+				turns = append(turns, []ai.AiMessage{m})
+			}
+		case len(turns) > 0:
+			turns[len(turns)-1] = append(turns[len(turns)-1], m)
+		}
+	}
+	return systems, turns
+}
+
+func joinTurns(turns [][]ai.AiMessage) []ai.AiMessage {
+	var msgs []ai.AiMessage
+	for _, turn := range turns {
+		msgs = append(msgs, turn...)
+	}
+	return msgs
+}
+
+// normalizeDialog 丢弃开头的非 user 消息，保证对话以 user 开头
+func normalizeDialog(msgs []ai.AiMessage) []ai.AiMessage {
+	for len(msgs) > 0 && msgs[0].Role != "user" {
+		msgs = msgs[1:]
+	}
+	return msgs
+}
@@ -0,0 +1,171 @@
+package pkg
+
+import (
+	"ai-stream-bot/client/ai"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func sys(content string) ai.AiMessage {
+	return ai.AiMessage{Role: "system", Content: content}
+}
+
+func user(content string) ai.AiMessage {
+	return ai.AiMessage{Role: "user", Content: content}
+}
+
+func assistant(content string) ai.AiMessage {
+	return ai.AiMessage{Role: "assistant", Content: content}
+}
+
+// long 生成约 n 个 token 的文本
+func long(n int) string {
+	return strings.Repeat("数", n)
+}
+
+func contents(msgs []ai.AiMessage) []string {
+	result := make([]string, len(msgs))
+	for i, m := range msgs {
+		result[i] = m.Role + ":" + m.Content
+	}
+	return result
+}
+
+// assertValidDialog 校验 system 消息在最前，之后以 user 开头且 user 与 assistant 交替
+func assertValidDialog(t *testing.T, msgs []ai.AiMessage) {
+	t.Helper()
+	i := 0
+	for i < len(msgs) && msgs[i].Role == "system" {
+		i++
+	}
+	expect := "user"
+	for ; i < len(msgs); i++ {
+		if msgs[i].Role != expect {
+			t.Fatalf("invalid alternation at %d: %v", i, contents(msgs))
+		}
+		if expect == "user" {
+			expect = "assistant"
+		} else {
+			expect = "user"
+		}
+	}
+}
+
+func TestTruncateStrategies(t *testing.T) {
+	history := []ai.AiMessage{
+		sys("s"),
+		user("u1 " + long(100)), assistant("a1 " + long(100)),
+		user("u2 " + long(100)), assistant("a2 " + long(100)),
+		user("u3"),
+	}
+
+	tests := []struct {
+		name     string
+		strategy TruncateStrategy
+		msgs     []ai.AiMessage
+		budget   int
+		want     []ai.AiMessage
+	}{
+		{
+			name:     "sliding window keeps all within budget",
+			strategy: &SlidingWindowStrategy{},
+			msgs:     history,
+			budget:   10000,
+			want:     history,
+		},
+		{
+			name:     "sliding window drops oldest messages and realigns to user",
+			strategy: &SlidingWindowStrategy{},
+			msgs:     history,
+			budget:   330,
+			want:     []ai.AiMessage{history[0], history[3], history[4], history[5]},
+		},
+		{
+			name:     "sliding window skips dangling assistant",
+			strategy: &SlidingWindowStrategy{},
+			msgs:     history,
+			budget:   150,
+			want:     []ai.AiMessage{history[0], history[5]},
+		},
+		{
+			name:     "sliding window keeps last turn over budget",
+			strategy: &SlidingWindowStrategy{},
+			msgs:     []ai.AiMessage{user(long(500))},
+			budget:   10,
+			want:     []ai.AiMessage{user(long(500))},
+		},
+		{
+			name:     "drop oldest pairs removes whole turns",
+			strategy: &DropOldestPairsStrategy{},
+			msgs:     history,
+			budget:   330,
+			want:     []ai.AiMessage{history[0], history[3], history[4], history[5]},
+		},
+		{
+			name:     "drop oldest pairs keeps system at odd position",
+			strategy: &DropOldestPairsStrategy{},
+			msgs:     []ai.AiMessage{user("u1 " + long(100)), sys("s"), assistant("a1"), user("u2")},
+			budget:   50,
+			want:     []ai.AiMessage{sys("s"), user("u2")},
+		},
+		{
+			name:     "last turns keeps system and last n turns",
+			strategy: &LastTurnsStrategy{Turns: 2},
+			msgs:     history,
+			budget:   0,
+			want:     []ai.AiMessage{history[0], history[3], history[4], history[5]},
+		},
+		{
+			name:     "last turns drops leading assistant",
+			strategy: &LastTurnsStrategy{Turns: 5},
+			msgs:     []ai.AiMessage{assistant("a0"), user("u1"), assistant("a1"), user("u2")},
+			budget:   0,
+			want:     []ai.AiMessage{user("u1"), assistant("a1"), user("u2")},
+		},
+		{
+			name:     "consecutive user messages keep the latest",
+			strategy: &LastTurnsStrategy{Turns: 5},
+			msgs:     []ai.AiMessage{user("u1"), assistant("a1"), user("failed"), user("u2")},
+			budget:   0,
+			want:     []ai.AiMessage{user("u1"), assistant("a1"), user("u2")},
+		},
+		{
+			name:     "empty history",
+			strategy: &DropOldestPairsStrategy{},
+			msgs:     nil,
+			budget:   100,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.strategy.Truncate(tt.msgs, tt.budget)
+			if !reflect.DeepEqual(contents(got), contents(tt.want)) {
+				t.Fatalf("got %v, want %v", contents(got), contents(tt.want))
+			}
+			assertValidDialog(t, got)
+		})
+	}
+}
+
+func TestNewTruncateStrategy(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepTurns int
+		want      TruncateStrategy
+	}{
+		{name: TruncateSlidingWindow, want: &SlidingWindowStrategy{}},
+		{name: TruncateDropOldestPairs, want: &DropOldestPairsStrategy{}},
+		{name: TruncateLastTurns, keepTurns: 3, want: &LastTurnsStrategy{Turns: 3}},
+		{name: TruncateLastTurns, want: &LastTurnsStrategy{Turns: DefaultKeepTurns}},
+		{name: "", want: &SlidingWindowStrategy{}},
+	}
+
+	for _, tt := range tests {
+		if got := NewTruncateStrategy(tt.name, tt.keepTurns); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("NewTruncateStrategy(%q, %d) = %#v, want %#v", tt.name, tt.keepTurns, got, tt.want)
+		}
+	}
+}
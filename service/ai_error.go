@@ -0,0 +1,45 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"errors"
+	"fmt"
+)
+
+// errorExplanation 错误在卡片中的说明与建议操作
+type errorExplanation struct {
+	kind   error
+	title  string
+	advice string
+}
+
+var errorExplanations = []errorExplanation{
+	{ai.ErrRateLimited, "⏳ 请求过于频繁", "模型服务触发了限流，请稍等片刻后重试，或发送 */model* 切换其它模型"},
+	{ai.ErrQuotaExceeded, "💰 额度已用尽", "模型服务的账户余额或调用配额不足，请联系管理员充值或调整配额，也可以发送 */model* 切换其它模型"},
+	{ai.ErrAuthFailed, "🔑 鉴权失败", "模型服务拒绝了请求的凭证，请联系管理员检查配置中的 api_key 及接入点权限"},
+	{ai.ErrContextTooLong, "📏 上下文过长", "本次对话超出了模型的上下文长度，请发送 */clear* 开始新会话，或精简问题后重试"},
+	{ai.ErrContentFiltered, "🚫 内容未通过安全审核", "提问或回答触发了模型服务的内容安全策略，请调整措辞后重试"},
+	{ai.ErrTimeout, "⌛ 请求超时", "模型服务长时间没有响应，请稍后重试，问题较复杂时可以拆分后提问"},
+	{ai.ErrUpstream5xx, "🛠 模型服务异常", "模型服务暂时不可用，请稍后重试，或发送 */model* 切换其它模型"},
+}
+
+// explainError 将请求错误转换为卡片中展示的说明，附带原始错误便于管理员排查
+func explainError(err error) string {
+	title, advice := "❌ 聊天失败", "请稍后重试，多次失败请联系管理员"
+	for _, e := range errorExplanations {
+		if errors.Is(err, e.kind) {
+			title, advice = e.title, e.advice
+			break
+		}
+	}
+	return fmt.Sprintf("**%s**\n%s\n\n*错误信息：%s*", title, advice, truncateRunes(err.Error(), 200))
+}
+
+// truncateRunes 按字符截断过长的内容，避免撑满卡片
+func truncateRunes(msg string, n int) string {
+	runes := []rune(msg)
+	if len(runes) <= n {
+		return msg
+	}
+	return string(runes[:n]) + "…"
+}
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"ai-stream-bot/client/ai"
 	"ai-stream-bot/client/im"
 	"ai-stream-bot/config"
 	"ai-stream-bot/consts"
 	"ai-stream-bot/model"
+	"ai-stream-bot/pkg"
 	"ai-stream-bot/pkg/feishu"
 	"strings"
 	"time"
@@ -62,7 +64,7 @@ type EmptyService struct {
 }
 
 func (s *EmptyService) Execute(action *model.MsgActionInfo) bool {
-	if action.ActionMsgInfo.Content == "" {
+	if action.ActionMsgInfo.Content == "" && len(action.ActionMsgInfo.ImageKeys) == 0 {
 		// 空消息，直接返回
 		card := feishu.BuildMessageCard(
 			feishu.BuildCardHeader("️🆑 DeepSeek友情提示", larkcard.TemplateGrey),
@@ -81,8 +83,14 @@ type CommandService struct {
 func (s *CommandService) Execute(action *model.MsgActionInfo) bool {
 	content := action.ActionMsgInfo.Content
 	commandGroups := map[string][]string{
-		"clearCommands": {"/clear", "开始新会话"},
-		"helpCommands":  {"/help", "帮助"},
+		"clearCommands":   {"/clear", "开始新会话"},
+		"helpCommands":    {"/help", "帮助"},
+		"modelCommands":   {"/model", "切换模型"},
+		"personaCommands": {"/persona", "设置人设"},
+		"kbCommands":      {"/kb"},
+		"regenCommands":   {"/regenerate", "重新生成"},
+		"paramsCommands":  {"/params"},
+		"usageCommands":   {"/usage"},
 	}
 
 	commandActions := map[string]func(){
@@ -110,6 +118,20 @@ func (s *CommandService) Execute(action *model.MsgActionInfo) bool {
 					}, larkcard.MessageCardButtonTypeDanger),
 				),
 				feishu.BuildCardSplitLine(),
+				feishu.BuildCardMainMd("🤖 **切换模型**\n文本回复 */model* 或 */model 服务名/模型名*"),
+				feishu.BuildCardSplitLine(),
+				feishu.BuildCardMainMd("🔄 **重新生成**\n点击回答下方的按钮，或文本回复 */regenerate* 、*/regenerate 服务名/模型名* 换个模型重新回答"),
+				feishu.BuildCardSplitLine(),
+				feishu.BuildCardMainMd("⚙️ **生成参数**\n文本回复 */params* 查看，*/params temperature=0 seed=42* 设置本话题的参数，*/params reset* 恢复默认"),
+				feishu.BuildCardSplitLine(),
+				feishu.BuildCardMainMd("📊 **用量统计**\n文本回复 */usage* 查看你和本群累计的 token 用量"),
+				feishu.BuildCardSplitLine(),
+				feishu.BuildCardMainMd("🎭 **设置人设**\n文本回复 */persona set 设定*、*/persona show* 或 */persona reset*"),
+				feishu.BuildCardSplitLine(),
+				feishu.BuildCardMainMd("📚 **知识库**\n文本回复 */kb add 文本*、*/kb list* 或 */kb delete 编号*，提问时自动检索"),
+				feishu.BuildCardSplitLine(),
+				feishu.BuildCardMainMd("📄 **文件问答**\n单聊发送 PDF、DOCX、TXT 或 Markdown 文件，回复文件卡片即可提问"),
+				feishu.BuildCardSplitLine(),
 				feishu.BuildCardMainMd("🎒 **需要更多帮助**\n文本回复 *帮助* 或 */help*"),
 				feishu.BuildCardSplitLine(),
 				feishu.BuildCardMainMd("🎒 **有啥想法反馈，请随时告诉我！**"),
@@ -117,6 +139,42 @@ func (s *CommandService) Execute(action *model.MsgActionInfo) bool {
 			cardStr, _ := card.String()
 			im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
 		},
+		"modelCommands": func() {
+			sessionId := *action.ActionMsgInfo.SessionId
+			arg, _ := pkg.EitherCutPrefix(content, commandGroups["modelCommands"]...)
+			var card *larkcard.MessageCard
+			if strings.TrimSpace(arg) == "" {
+				card = buildModelListCard(action.ActionMsgInfo.ChatType, sessionId, *action.ActionMsgInfo.MsgId,
+					action.SessionCache.GetModel(sessionId))
+			} else {
+				card = buildModelPinnedCard(pinSessionModel(action.SessionCache, sessionId, arg))
+			}
+			cardStr, _ := card.String()
+			im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+		},
+		"personaCommands": func() {
+			arg, _ := pkg.EitherCutPrefix(content, commandGroups["personaCommands"]...)
+			cardStr, _ := handlePersonaCommand(action, arg).String()
+			im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+		},
+		"kbCommands": func() {
+			arg, _ := pkg.EitherCutPrefix(content, commandGroups["kbCommands"]...)
+			cardStr, _ := handleKBCommand(action, arg).String()
+			im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+		},
+		"paramsCommands": func() {
+			arg, _ := pkg.EitherCutPrefix(content, commandGroups["paramsCommands"]...)
+			cardStr, _ := handleParamsCommand(action, arg).String()
+			im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+		},
+		"usageCommands": func() {
+			cardStr, _ := handleUsageCommand(action).String()
+			im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+		},
+		"regenCommands": func() {
+			arg, _ := pkg.EitherCutPrefix(content, commandGroups["regenCommands"]...)
+			NewFeishuMsgService(ai.GetManager()).Regenerate(action, strings.TrimSpace(arg), nil)
+		},
 	}
 
 	for group, cmds := range commandGroups {
@@ -135,7 +193,7 @@ type ClearCardService struct {
 
 func (s *ClearCardService) Execute(action *model.CardActionInfo) (*larkcard.MessageCard, bool) {
 	if action.Kind != consts.ClearCard {
-		return nil, false
+		return nil, true
 	}
 	// 清除上下文
 	action.SessionCache.Clear(action.SessionId)
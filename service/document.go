@@ -0,0 +1,110 @@
+package service
+
+import (
+	"ai-stream-bot/client/im"
+	"ai-stream-bot/consts"
+	"ai-stream-bot/dal/cache"
+	"ai-stream-bot/model"
+	"ai-stream-bot/pkg/document"
+	"ai-stream-bot/pkg/feishu"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// documentInstruction 文件内容 system 消息的前缀
+const documentInstruction = "以下是用户在本话题中上传的文件内容，篇幅较长时只包含与问题相关的片段。请结合文件内容回答用户的问题，文件中没有的信息请如实说明。\n"
+
+// FileService 处理文件消息，提取文本后附加到会话供后续提问
+type FileService struct {
+}
+
+func (s *FileService) Execute(action *model.MsgActionInfo) bool {
+	if action.ActionMsgInfo.MsgType != consts.MsgTypeFile {
+		return true
+	}
+	doc, err := ingestFile(action)
+	if err != nil {
+		hlog.Errorf("ingest file %s error: %v", action.ActionMsgInfo.FileName, err)
+	}
+	cardStr, _ := buildFileCard(action.ActionMsgInfo.FileName, doc, err).String()
+	im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+	return false
+}
+
+// ingestFile 下载并解析文件，分块后保存到会话
+func ingestFile(action *model.MsgActionInfo) (*cache.SessionDocument, error) {
+	info := action.ActionMsgInfo
+	data, err := im.GetFeishuClient().FeishuGetMessageResource(action.Ctx, *info.MsgId, info.FileKey, "file", consts.MaxFileSize)
+	if errors.Is(err, im.ErrResourceTooLarge) {
+		return nil, fmt.Errorf("文件超过 %dMB，暂不支持", consts.MaxFileSize>>20)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("文件下载失败，请稍后重新发送")
+	}
+	doc, err := document.Parse(info.FileName, data)
+	if errors.Is(err, document.ErrUnsupported) {
+		return nil, fmt.Errorf("暂只支持 PDF、DOCX、TXT 和 Markdown 文件")
+	}
+	if errors.Is(err, document.ErrTooLarge) {
+		return nil, fmt.Errorf("文件内容过多，暂不支持")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("文件解析失败：%v", err)
+	}
+	if doc.Text == "" {
+		return nil, fmt.Errorf("未能从文件中提取到文字，扫描件暂不支持")
+	}
+	sessionDoc := &cache.SessionDocument{
+		Name:   doc.Name,
+		Pages:  doc.Pages,
+		Chars:  doc.Chars(),
+		Chunks: document.Chunk(doc.Text, document.DefaultChunkTokens),
+	}
+	action.SessionCache.AddDocument(*info.SessionId, sessionDoc)
+	hlog.Infof("UserId: %s , ingest file: %s , pages: %d , chars: %d , chunks: %d",
+		info.UserId, sessionDoc.Name, sessionDoc.Pages, sessionDoc.Chars, len(sessionDoc.Chunks))
+	return sessionDoc, nil
+}
+
+// buildFileCard 构建文件读取结果卡片
+func buildFileCard(fileName string, doc *cache.SessionDocument, err error) *larkcard.MessageCard {
+	if err != nil {
+		return feishu.BuildMessageCard(
+			feishu.BuildCardHeader("📄 文件读取失败", larkcard.TemplateRed),
+			feishu.BuildCardMainMd(fmt.Sprintf("**%s**", fileName)),
+			feishu.BuildCardNote(err.Error()),
+		)
+	}
+	pages := "-"
+	if doc.Pages > 0 {
+		pages = fmt.Sprint(doc.Pages)
+	}
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader("📄 文件已读取", larkcard.TemplateGreen),
+		feishu.BuildCardMainMd(fmt.Sprintf("**%s**\n页数：%s ｜ 字数：%d ｜ 分块：%d", doc.Name, pages, doc.Chars, len(doc.Chunks))),
+		feishu.BuildCardNote("回复此消息即可针对文件内容提问，发送 /clear 可清除"),
+	)
+}
+
+// documentPrompt 按 token 预算选取会话文件中与问题相关的分块，拼接为 system 消息内容
+func documentPrompt(docs []*cache.SessionDocument, query string, budget int) string {
+	if len(docs) == 0 || budget <= 0 {
+		return ""
+	}
+	var prompt strings.Builder
+	for _, doc := range docs {
+		chunks := document.Select(doc.Chunks, query, budget/len(docs))
+		if len(chunks) == 0 {
+			continue
+		}
+		prompt.WriteString(fmt.Sprintf("\n《%s》\n%s\n", doc.Name, strings.Join(chunks, "\n……\n")))
+	}
+	if prompt.Len() == 0 {
+		return ""
+	}
+	return documentInstruction + prompt.String()
+}
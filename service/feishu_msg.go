@@ -3,8 +3,13 @@ package service
 import (
 	"ai-stream-bot/client/ai"
 	"ai-stream-bot/client/im"
+	"ai-stream-bot/config"
+	"ai-stream-bot/dal/kb"
 	"ai-stream-bot/model"
+	"ai-stream-bot/pkg"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -12,151 +17,310 @@ import (
 	"github.com/cloudwego/hertz/pkg/common/hlog"
 )
 
+// noContentWait 请求发出后等待首个输出的时间，超时后按请求超时结束
+const noContentWait = 10 * time.Second
+
 type FeishuMsgService struct {
 	aiManager *ai.Manager
+	router    *ModelRouter
 }
 
 func NewFeishuMsgService(aiManager *ai.Manager) *FeishuMsgService {
 	return &FeishuMsgService{
 		aiManager: aiManager,
+		router:    NewModelRouter(config.GetAIRoutes()),
 	}
 }
 
 func (s *FeishuMsgService) Execute(action *model.MsgActionInfo) bool {
+	content := action.ActionMsgInfo.Content
+	provider, modelName := "", ""
+	if route := s.router.Route(action.ActionMsgInfo); route != nil {
+		provider, modelName = route.Provider, route.Model
+		content = strings.TrimSpace(strings.TrimPrefix(content, route.Prefix))
+		hlog.Infof("UserId: %s , hit route: %s , provider: %s , model: %s", action.ActionMsgInfo.UserId, route.Name, provider, modelName)
+	}
+	// 通过 /model 固定的模型优先于路由规则
+	if pinned := action.SessionCache.GetModel(*action.ActionMsgInfo.SessionId); pinned != nil {
+		provider, modelName = pinned.Provider, pinned.Model
+	}
+
+	if len(action.ActionMsgInfo.ImageKeys) > 0 && !s.aiManager.SupportsVision(provider, modelName) {
+		cardStr, _ := buildVisionUnsupportedCard(provider, s.aiManager.GetModel(provider, modelName)).String()
+		im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+		return false
+	}
+	userMsg, err := buildUserMessage(action, content)
+	if err != nil {
+		hlog.Errorf("buildUserMessage returned error: %v", err)
+		cardStr, _ := buildImageFailedCard().String()
+		im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+		return false
+	}
+
+	msg := action.SessionCache.GetMsg(*action.ActionMsgInfo.SessionId)
+	msg = append(msg, userMsg)
+	s.streamReply(action, &replyPlan{
+		Provider: provider,
+		Model:    modelName,
+		Msgs:     msg,
+		Query:    content,
+		Save: func(answer string) []ai.AiMessage {
+			return append(msg, ai.AiMessage{Role: "assistant", Content: answer})
+		},
+	})
+	return false
+}
+
+// replyPlan 一次流式回答的输入，普通提问、重新生成和继续生成共用同一流程
+type replyPlan struct {
+	Provider string
+	Model    string
+	// Msgs 发送给模型的会话消息，不含 system 消息
+	Msgs []ai.AiMessage
+	// Query 用于检索文件和知识库的问题
+	Query string
+	// Save 根据本次回答返回需要写回会话的消息
+	Save func(answer string) []ai.AiMessage
+}
+
+// streamReply 回复流式卡片并将模型输出实时更新到卡片，结束后保存会话
+func (s *FeishuMsgService) streamReply(action *model.MsgActionInfo, plan *replyPlan) {
+	provider, modelName, content := plan.Provider, plan.Model, plan.Query
+
 	// 1. 返回一个流式卡片
 	cardId, _, err := s.sendEditableCard(action)
 	if err != nil {
 		hlog.Errorf("sendEditableCard returned error: %v", err)
-		return false
+		return
 	}
+	// 卡片上的停止按钮通过取消 ctx 结束生成
+	ctx, cancel := context.WithCancel(action.Ctx)
+	defer cancel()
+	GetGenerationRegistry().Register(*cardId, *action.ActionMsgInfo.SessionId, cancel)
+	defer GetGenerationRegistry().Unregister(*cardId)
 
 	thinkingAnswer := "> "
-	referenceAnswer := ""
+	toolAnswer := ""
+	references := newReferenceList()
 	streamAnswer := ""
+	// output 模型输出的思考和回答，用于在模型服务未返回用量时估算
+	var output strings.Builder
 
-	refStream := make(chan string)
-	thinkStream := make(chan string)
-	answerResponseStream := make(chan string)
-	done := make(chan struct{})
-
-	noContentTimeout := time.AfterFunc(10*time.Second, func() {
-		hlog.Info("no content timeout")
-		close(done)
-		err := im.GetFeishuClient().FeishuUpdateCard(action.Ctx, model.StreamUpdateMessage{Answer: "请求超时"}, *cardId)
-		if err != nil {
-			return
+	// 按模型的上下文窗口裁剪历史消息
+	contextCfg := config.GetContextConfig()
+	budget := config.GetContextBudget(s.aiManager.GetModel(provider, modelName))
+	// 人设、文件和知识库内容作为 system 消息置于最前，不写入会话历史
+	reqMsgs := plan.Msgs
+	// 不支持图片的模型只发送历史图片消息中的文本
+	if !s.aiManager.SupportsVision(provider, modelName) {
+		reqMsgs = ai.TextOnly(reqMsgs)
+	}
+	var systems []ai.AiMessage
+	if prompt := systemPrompt(action); prompt != "" {
+		systems = append(systems, ai.AiMessage{Role: "system", Content: prompt})
+	}
+	// 会话中上传的文件最多占用一半的输入预算
+	if prompt := documentPrompt(action.SessionCache.GetDocuments(*action.ActionMsgInfo.SessionId), content, budget/2); prompt != "" {
+		systems = append(systems, ai.AiMessage{Role: "system", Content: prompt})
+	}
+	// 检索知识库，来源作为前几条引用
+	var kbHits []kb.Hit
+	if config.IsKBEnabled() && action.ActionMsgInfo.ChatId != nil {
+		kbHits = NewKnowledgeBase(s.aiManager, config.GetKBConfig()).Retrieve(action.Ctx, *action.ActionMsgInfo.ChatId, content)
+		if prompt := kbPrompt(kbHits); prompt != "" {
+			systems = append(systems, ai.AiMessage{Role: "system", Content: prompt})
 		}
-		im.GetFeishuClient().FeishuUpdateCardSetting(action.Ctx, *cardId)
-	})
+	}
+	reqMsgs = append(systems, reqMsgs...)
+	reqMsgs = pkg.NewTruncateStrategy(contextCfg.Truncate, contextCfg.KeepTurns).Truncate(reqMsgs, budget)
+	chatReq := &ai.AiChatStreamRequest{
+		Msgs:   reqMsgs,
+		Model:  modelName,
+		Params: action.SessionCache.GetParams(*action.ActionMsgInfo.SessionId),
+	}
+	if config.IsToolsEnabled() {
+		chatReq.Tools = ai.GetToolRegistry().Definitions()
+	}
+	// 知识库来源先于模型返回的引用加入，编号与提示词中的一致
+	for _, ref := range kbReferences(kbHits) {
+		references.AddPrompt(ref)
+	}
 
+	events := s.aiManager.StreamEvents(ctx, provider, chatReq)
+	noContentTimeout := time.NewTimer(noContentWait)
 	defer noContentTimeout.Stop()
-
-	msg := action.SessionCache.GetMsg(*action.ActionMsgInfo.SessionId)
-	msg = append(msg, ai.AiMessage{
-		Role: "user", Content: *&action.ActionMsgInfo.Content,
-	})
-	go func() {
-		defer func() {
-			if err := recover(); err != nil {
-				err := im.GetFeishuClient().FeishuUpdateCard(action.Ctx, model.StreamUpdateMessage{Answer: "聊天失败"}, *cardId)
-				if err != nil {
-					hlog.Errorf("FeishuUpdateCard returned error: %v", err)
-					return
-				}
-				im.GetFeishuClient().FeishuUpdateCardSetting(action.Ctx, *cardId)
-			}
-		}()
-
-		if err := s.aiManager.StreamChat(action.Ctx, &ai.AiChatStreamRequest{
-			Msgs:         msg,
-			ThinkStream:  thinkStream,
-			AnswerStream: answerResponseStream,
-			RefStream:    refStream,
-		}); err != nil {
-			err := im.GetFeishuClient().FeishuUpdateCard(action.Ctx, model.StreamUpdateMessage{Answer: "聊天失败"}, *cardId)
-			if err != nil {
-				hlog.Errorf("FeishuUpdateCard returned error: %v", err)
-				return
-			}
-			im.GetFeishuClient().FeishuUpdateCardSetting(action.Ctx, *cardId)
-			close(done) // 关闭 done 信号
-		}
-
-		close(done) // 关闭 done 信号
-	}()
 	ticker := time.NewTicker(700 * time.Millisecond)
 	defer ticker.Stop()
-	go func() {
-		for {
-			select {
-			case <-done:
-				return
-			case <-ticker.C:
-				updateMsg := model.StreamUpdateMessage{
-					Thinking: thinkingAnswer,
-					Answer:   streamAnswer,
-				}
-				err := im.GetFeishuClient().FeishuUpdateCard(action.Ctx, updateMsg, *cardId)
-				if err != nil {
-					hlog.Errorf("FeishuUpdateCard returned error: %v", err)
-					return
-				}
-			}
-		}
-	}()
 
-	for {
+	// genErr 为空且 finished 为 false 时表示被停止生成
+	var genErr error
+	var usage *ai.Usage
+	finishReason := ""
+	finished := false
+	for running := true; running; {
 		select {
-		case think, ok := <-thinkStream:
-			if !ok {
-				continue
-			}
-			noContentTimeout.Stop()
-			thinkingAnswer += think
-			thinkingAnswer = strings.ReplaceAll(thinkingAnswer, "\n\n", "\n>")
-			hlog.Errorf("think: %s", thinkingAnswer)
-		case ref, ok := <-refStream:
+		case event, ok := <-events:
 			if !ok {
-				continue
+				running = false
+				break
 			}
-			noContentTimeout.Stop()
-			referenceAnswer += ref
-		case res, ok := <-answerResponseStream:
-			if !ok {
-				continue
+			switch event.Kind {
+			case ai.EventThinkDelta:
+				noContentTimeout.Stop()
+				output.WriteString(event.Text)
+				thinkingAnswer += event.Text
+				thinkingAnswer = strings.ReplaceAll(thinkingAnswer, "\n\n", "\n>")
+				hlog.Errorf("think: %s", thinkingAnswer)
+			case ai.EventAnswerDelta:
+				noContentTimeout.Stop()
+				output.WriteString(event.Text)
+				streamAnswer += event.Text
+			case ai.EventReference:
+				// 联网搜索的引用先于思考和回答返回
+				noContentTimeout.Stop()
+				references.Add(*event.Reference)
+			case ai.EventToolCall:
+				noContentTimeout.Stop()
+				toolAnswer += formatToolCall(event.ToolCall)
+			case ai.EventUsage:
+				usage = event.Usage
+			case ai.EventFinish:
+				finished = true
+				finishReason = event.Reason
+				// 输出被内容安全策略拦截时同样按错误展示
+				if finishReason == ai.FinishReasonContentFilter {
+					genErr = &ai.Error{Kind: ai.ErrContentFiltered, Provider: chatReq.ServedBy, Message: "finish_reason " + finishReason}
+				}
+			case ai.EventError:
+				// 用户停止生成时按正常结束处理，保留已生成的内容
+				if ctx.Err() == nil || action.Ctx.Err() != nil {
+					genErr = event.Err
+				}
 			}
-			noContentTimeout.Stop()
-			streamAnswer += res
-		case <-done:
+		case <-ticker.C:
 			updateMsg := model.StreamUpdateMessage{
-				Thinking:  thinkingAnswer,
-				Reference: referenceAnswer,
-				Answer:    streamAnswer,
+				Thinking: thinkingAnswer,
+				Tool:     toolAnswer,
+				Answer:   streamAnswer,
 			}
-			err := im.GetFeishuClient().FeishuUpdateCard(action.Ctx, updateMsg, *cardId)
-			if err != nil {
+			if err := im.GetFeishuClient().FeishuUpdateCard(action.Ctx, updateMsg, *cardId); err != nil {
 				hlog.Errorf("FeishuUpdateCard returned error: %v", err)
-				return false
 			}
-			im.GetFeishuClient().FeishuUpdateCardSetting(action.Ctx, *cardId)
-			ticker.Stop()
-			combinedAnswer := thinkingAnswer + "\n" + streamAnswer + "\n" + referenceAnswer
-			msg = append(msg, ai.AiMessage{
-				Role:    "assistant",
-				Content: streamAnswer,
-			})
-			action.SessionCache.SetMsg(*action.ActionMsgInfo.SessionId, msg)
-
-			jsonByteArray, err := json.Marshal(msg)
-			if err != nil {
-				hlog.Errorf("Error marshaling JSON request: UserId: %s , Request: %s , Response: %s", action.ActionMsgInfo.UserId, jsonByteArray, combinedAnswer)
+		case <-noContentTimeout.C:
+			hlog.Info("no content timeout")
+			genErr = fmt.Errorf("no content returned in %s: %w", noContentWait, ai.ErrTimeout)
+			// 取消后等待事件流关闭，之后才能读取 chatReq
+			cancel()
+			for event := range events {
+				if event.Kind == ai.EventUsage {
+					usage = event.Usage
+				}
 			}
-			jsonStr := strings.ReplaceAll(string(jsonByteArray), "\\n", "")
-			jsonStr = strings.ReplaceAll(jsonStr, "\n", "")
-			hlog.Infof("UserId: %s , Request: %s , Response: %s", action.ActionMsgInfo.UserId, jsonStr, combinedAnswer)
-			return false
+			running = false
 		}
 	}
+	hlog.Infof("UserId: %s , served by client: %s", action.ActionMsgInfo.UserId, chatReq.ServedBy)
+	if genErr == nil && !finished {
+		hlog.Infof("UserId: %s , generation stopped", action.ActionMsgInfo.UserId)
+	}
+	// 事件流已关闭，ctx 取消时未送达的用量从请求中读取
+	if usage == nil {
+		usage = chatReq.Usage
+	}
+	// 停止生成、超时或输出后出错时模型服务通常不返回用量，按请求和已输出的内容估算
+	// 未输出内容就出错的请求（如鉴权失败）通常未被处理，不记录用量
+	if usage == nil && (output.Len() > 0 || genErr == nil || errors.Is(genErr, ai.ErrTimeout)) {
+		usage = estimateUsage(chatReq.Msgs, output.String())
+	}
+	s.recordUsage(action, chatReq.ServedBy, chatReq.Model, usage)
+
+	updateMsg := model.StreamUpdateMessage{
+		Thinking:  thinkingAnswer,
+		Tool:      toolAnswer,
+		Reference: references.Footnotes(),
+		Answer:    references.LinkMarkers(streamAnswer),
+	}
+	if genErr != nil {
+		// 出错时保留已输出的内容，附上错误说明，不写入会话
+		hlog.Errorf("UserId: %s , stream chat error: %v", action.ActionMsgInfo.UserId, genErr)
+		if updateMsg.Answer != "" {
+			updateMsg.Answer += "\n\n"
+		}
+		updateMsg.Answer += explainError(genErr)
+	} else if !finished {
+		updateMsg.Answer += "\n\n*（已停止生成）*"
+	}
+	if err := im.GetFeishuClient().FeishuUpdateCard(action.Ctx, updateMsg, *cardId); err != nil {
+		hlog.Errorf("FeishuUpdateCard returned error: %v", err)
+		return
+	}
+	finishStreamingCard(action.Ctx, *cardId)
+	if genErr != nil {
+		return
+	}
+	combinedAnswer := thinkingAnswer + "\n" + streamAnswer + "\n" + references.Footnotes()
+	msg := plan.Save(streamAnswer)
+	action.SessionCache.SetMsg(*action.ActionMsgInfo.SessionId, msg)
+	appendReplyButtons(action, plan, *cardId, finishReason)
+	if contextCfg.Truncate == pkg.TruncateSummarize {
+		NewHistorySummarizer(s.aiManager, contextCfg.Summary).MaybeSummarize(action.SessionCache, *action.ActionMsgInfo.SessionId)
+	}
+
+	jsonByteArray, err := json.Marshal(ai.TextOnly(msg))
+	if err != nil {
+		hlog.Errorf("Error marshaling JSON request: UserId: %s , Request: %s , Response: %s", action.ActionMsgInfo.UserId, jsonByteArray, combinedAnswer)
+	}
+	jsonStr := strings.ReplaceAll(string(jsonByteArray), "\\n", "")
+	jsonStr = strings.ReplaceAll(jsonStr, "\n", "")
+	hlog.Infof("UserId: %s , Request: %s , Response: %s", action.ActionMsgInfo.UserId, jsonStr, combinedAnswer)
+}
+
+// formatToolCall 将工具调用事件格式化为卡片中的一行
+func formatToolCall(call *ai.ToolCallEvent) string {
+	switch {
+	case !call.Done:
+		return fmt.Sprintf("🔧 调用 **%s** `%s`\n", call.Call.Function.Name, call.Call.Function.Arguments)
+	case call.Err != nil:
+		return fmt.Sprintf("❌ %s\n", call.Err)
+	default:
+		return fmt.Sprintf("✅ %s\n", truncateRunes(call.Result, 100))
+	}
+}
+
+// recordUsage 记录本次请求的 token 用量，并按用户和群聊汇总
+func (s *FeishuMsgService) recordUsage(action *model.MsgActionInfo, servedBy, modelName string, usage *ai.Usage) {
+	if usage == nil || action.UsageCache == nil {
+		return
+	}
+	chatId := ""
+	if action.ActionMsgInfo.ChatId != nil {
+		chatId = *action.ActionMsgInfo.ChatId
+	}
+	userStat, chatStat := action.UsageCache.Record(action.ActionMsgInfo.UserId, chatId, usage)
+	hlog.Infof("UserId: %s , ChatId: %s , Client: %s , Model: %s , PromptTokens: %d , CompletionTokens: %d , ReasoningTokens: %d , TotalTokens: %d , Estimated: %t , UserTotalTokens: %d , UserEstimatedTokens: %d , ChatTotalTokens: %d , ChatEstimatedTokens: %d",
+		action.ActionMsgInfo.UserId, chatId, servedBy, modelName,
+		usage.PromptTokens, usage.CompletionTokens, usage.ReasoningTokens, usage.TotalTokens, usage.Estimated,
+		userStat.TotalTokens, userStat.EstimatedUsage.TotalTokens, chatStat.TotalTokens, chatStat.EstimatedUsage.TotalTokens)
+}
+
+// estimateUsage 按请求消息和模型输出估算用量
+func estimateUsage(msgs []ai.AiMessage, output string) *ai.Usage {
+	usage := &ai.Usage{
+		PromptTokens:     pkg.EstimateMessagesTokens(msgs),
+		CompletionTokens: pkg.EstimateTokens(output),
+		Estimated:        true,
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	return usage
+}
+
+// finishStreamingCard 移除停止按钮并结束卡片的流式更新
+func finishStreamingCard(ctx context.Context, cardId string) {
+	if err := im.GetFeishuClient().FeishuDeleteCardElement(ctx, cardId, im.StopButtonElementId); err != nil {
+		hlog.Errorf("FeishuDeleteCardElement returned error: %v", err)
+	}
+	im.GetFeishuClient().FeishuUpdateCardSetting(ctx, cardId)
 }
 
 func (s *FeishuMsgService) sendEditableCard(action *model.MsgActionInfo) (*string, *string, error) {
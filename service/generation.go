@@ -0,0 +1,96 @@
+package service
+
+import (
+	"ai-stream-bot/consts"
+	"ai-stream-bot/model"
+	"context"
+	"sync"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// GenerationRegistry 进行中的生成，按流式卡片 ID 保存取消函数，并按会话记录进行中的生成数
+type GenerationRegistry struct {
+	mu          sync.Mutex
+	generations map[string]generation
+	// sessions 会话中进行中和已预约的生成数
+	sessions map[string]int
+}
+
+type generation struct {
+	sessionId string
+	cancel    context.CancelFunc
+}
+
+var generationRegistry = &GenerationRegistry{generations: map[string]generation{}, sessions: map[string]int{}}
+
+func GetGenerationRegistry() *GenerationRegistry {
+	return generationRegistry
+}
+
+func (r *GenerationRegistry) Register(cardId, sessionId string, cancel context.CancelFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generations[cardId] = generation{sessionId: sessionId, cancel: cancel}
+	r.sessions[sessionId]++
+}
+
+func (r *GenerationRegistry) Unregister(cardId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if g, ok := r.generations[cardId]; ok {
+		delete(r.generations, cardId)
+		r.release(g.sessionId)
+	}
+}
+
+// Reserve 会话中没有进行中的生成时预约一次生成并返回 true，结束后需调用 Release
+// 用于重新生成和继续生成，避免与进行中的回答同时改写会话
+func (r *GenerationRegistry) Reserve(sessionId string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.sessions[sessionId] > 0 {
+		return false
+	}
+	r.sessions[sessionId]++
+	return true
+}
+
+func (r *GenerationRegistry) Release(sessionId string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.release(sessionId)
+}
+
+func (r *GenerationRegistry) release(sessionId string) {
+	if r.sessions[sessionId]--; r.sessions[sessionId] <= 0 {
+		delete(r.sessions, sessionId)
+	}
+}
+
+// Cancel 取消卡片对应的生成，生成已结束时返回 false
+func (r *GenerationRegistry) Cancel(cardId string) bool {
+	r.mu.Lock()
+	g, ok := r.generations[cardId]
+	r.mu.Unlock()
+	if ok {
+		g.cancel()
+	}
+	return ok
+}
+
+// StopCardService 处理流式卡片上的停止按钮，卡片由生成流程负责收尾
+type StopCardService struct {
+}
+
+func (s *StopCardService) Execute(action *model.CardActionInfo) (*larkcard.MessageCard, bool) {
+	if action.Kind != consts.StopCard {
+		return nil, true
+	}
+	if GetGenerationRegistry().Cancel(action.CardId) {
+		action.Toast = "已停止生成"
+	} else {
+		action.Toast = "回答已结束"
+	}
+	return nil, true
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/config"
+	"ai-stream-bot/dal/kb"
+	"ai-stream-bot/pkg/document"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	// kbInstruction 知识库检索结果 system 消息的前缀
+	kbInstruction = "以下是知识库中与用户问题相关的资料，回答时请优先参考，并用 [资料编号] 标注引用的资料，如 [资料1]；资料与问题无关时请忽略。\n"
+	// kbEmbedTimeout 检索时向量化问题的超时，超时后按关键词检索
+	kbEmbedTimeout = 5 * time.Second
+	// kbEmbedBatch 每次向量化的分块数
+	kbEmbedBatch = 16
+)
+
+// KnowledgeBase 知识库的写入与检索，未配置向量化服务时按关键词检索
+type KnowledgeBase struct {
+	aiManager *ai.Manager
+	cfg       *config.KBConfig
+}
+
+func NewKnowledgeBase(aiManager *ai.Manager, cfg *config.KBConfig) *KnowledgeBase {
+	return &KnowledgeBase{aiManager: aiManager, cfg: cfg}
+}
+
+// Add 将文本分块、向量化后写入群聊的知识库
+func (k *KnowledgeBase) Add(ctx context.Context, chatId, userId, source, text string) (*kb.Entry, error) {
+	texts := document.Chunk(text, k.cfg.ChunkTokens)
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("内容不能为空")
+	}
+	entry := &kb.Entry{Source: source, CreatedBy: userId}
+	for _, t := range texts {
+		entry.Chunks = append(entry.Chunks, &kb.Chunk{Text: t})
+	}
+	if k.cfg.Embedder != "" {
+		for batch := range slices.Chunk(entry.Chunks, kbEmbedBatch) {
+			inputs := make([]string, len(batch))
+			for i, c := range batch {
+				inputs[i] = c.Text
+			}
+			vectors, err := k.embed(ctx, inputs)
+			if err != nil {
+				return nil, fmt.Errorf("向量化失败：%v", err)
+			}
+			for i, c := range batch {
+				c.Vector = vectors[i]
+			}
+		}
+	}
+	if _, err := kb.GetStore().Add(chatId, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// Retrieve 检索与问题相关的分块，出错时返回空结果，不影响正常对话
+func (k *KnowledgeBase) Retrieve(ctx context.Context, chatId, query string) []kb.Hit {
+	var vector []float32
+	if k.cfg.Embedder != "" {
+		embedCtx, cancel := context.WithTimeout(ctx, kbEmbedTimeout)
+		vectors, err := k.embed(embedCtx, []string{query})
+		cancel()
+		if err != nil {
+			hlog.Warnf("kb embed query error, fallback to keyword search: %v", err)
+		} else {
+			vector = vectors[0]
+		}
+	}
+	hits, err := kb.GetStore().Search(chatId, query, vector, k.cfg.TopK)
+	if err != nil {
+		hlog.Errorf("kb search error: %v", err)
+		return nil
+	}
+	return slices.DeleteFunc(hits, func(h kb.Hit) bool {
+		return h.Score < k.cfg.MinScore
+	})
+}
+
+func (k *KnowledgeBase) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embedder, err := k.aiManager.GetEmbedder(k.cfg.Embedder)
+	if err != nil {
+		return nil, err
+	}
+	return embedder.Embed(ctx, texts)
+}
+
+// kbSources 按来源去重，返回来源列表和每个分块对应的编号
+func kbSources(hits []kb.Hit) ([]string, []int) {
+	var sources []string
+	numbers := make([]int, len(hits))
+	for i, h := range hits {
+		idx := slices.Index(sources, h.Source)
+		if idx < 0 {
+			sources = append(sources, h.Source)
+			idx = len(sources) - 1
+		}
+		numbers[i] = idx + 1
+	}
+	return sources, numbers
+}
+
+// kbPrompt 将检索结果拼接为 system 消息内容，同一来源使用相同编号
+func kbPrompt(hits []kb.Hit) string {
+	if len(hits) == 0 {
+		return ""
+	}
+	_, numbers := kbSources(hits)
+	var prompt strings.Builder
+	prompt.WriteString(kbInstruction)
+	for i, h := range hits {
+		prompt.WriteString(fmt.Sprintf("\n[%s%d] %s\n%s\n", promptMarkerPrefix, numbers[i], h.Source, h.Text))
+	}
+	return prompt.String()
+}
+
+// kbReferences 检索结果的来源，顺序与 kbPrompt 中的编号一致
+func kbReferences(hits []kb.Hit) []ai.Reference {
+	sources, _ := kbSources(hits)
+	refs := make([]ai.Reference, len(sources))
+	for i, source := range sources {
+		refs[i] = ai.Reference{Title: "📚 " + source, Site: "知识库"}
+	}
+	return refs
+}
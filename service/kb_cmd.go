@@ -0,0 +1,110 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/config"
+	"ai-stream-bot/dal/kb"
+	"ai-stream-bot/model"
+	"ai-stream-bot/pkg"
+	"ai-stream-bot/pkg/feishu"
+	"fmt"
+	"strings"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+const kbUsage = "*/kb add 文本* 添加文本，在文件卡片下回复 */kb add* 添加文件\n*/kb list* 查看知识库，*/kb delete 编号* 删除条目"
+
+// handleKBCommand 处理 /kb add|list|delete，arg 为命令之后的内容
+func handleKBCommand(action *model.MsgActionInfo, arg string) *larkcard.MessageCard {
+	if !config.IsKBEnabled() {
+		return buildKBCard("📚 知识库未开启", larkcard.TemplateGrey, "请在配置文件中开启 kb")
+	}
+	chatId := *action.ActionMsgInfo.ChatId
+	arg = strings.TrimSpace(arg)
+
+	if text, ok := pkg.CutPrefix(arg, "add"); ok {
+		return handleKBAdd(action, chatId, strings.TrimSpace(text))
+	}
+	if _, ok := pkg.TrimEqual(arg, "list"); ok {
+		entries, err := kb.GetStore().List(chatId)
+		if err != nil {
+			return buildKBCard("📚 读取知识库失败", larkcard.TemplateRed, err.Error())
+		}
+		return buildKBListCard(entries)
+	}
+	if id, ok := pkg.CutPrefix(arg, "delete"); ok {
+		id = strings.TrimSpace(id)
+		deleted, err := kb.GetStore().Delete(chatId, id)
+		if err != nil {
+			return buildKBCard("📚 删除失败", larkcard.TemplateRed, err.Error())
+		}
+		if !deleted {
+			return buildKBCard("📚 删除失败", larkcard.TemplateRed, fmt.Sprintf("条目 %s 不存在，文本回复 */kb list* 查看编号", id))
+		}
+		return buildKBCard("📚 已删除", larkcard.TemplateGrey, fmt.Sprintf("已从知识库中删除条目 %s", id))
+	}
+	return buildKBCard("📚 知识库", larkcard.TemplateBlue, kbUsage)
+}
+
+// handleKBAdd 添加文本，未提供文本时添加当前话题中上传的文件
+func handleKBAdd(action *model.MsgActionInfo, chatId, text string) *larkcard.MessageCard {
+	knowledgeBase := NewKnowledgeBase(ai.GetManager(), config.GetKBConfig())
+	userId := action.ActionMsgInfo.UserId
+	if text != "" {
+		entry, err := knowledgeBase.Add(action.Ctx, chatId, userId, kbTextSource(text), text)
+		if err != nil {
+			return buildKBCard("📚 添加失败", larkcard.TemplateRed, err.Error())
+		}
+		return buildKBCard("📚 已添加到知识库", larkcard.TemplateGreen,
+			fmt.Sprintf("编号 %s ｜ 分块 %d\n群内提问时会自动检索相关内容", entry.ID, len(entry.Chunks)))
+	}
+
+	docs := action.SessionCache.GetDocuments(*action.ActionMsgInfo.SessionId)
+	if len(docs) == 0 {
+		return buildKBCard("📚 添加失败", larkcard.TemplateRed, "当前话题中没有文件\n"+kbUsage)
+	}
+	var lines []string
+	for _, doc := range docs {
+		entry, err := knowledgeBase.Add(action.Ctx, chatId, userId, doc.Name, strings.Join(doc.Chunks, "\n"))
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("❌ %s：%v", doc.Name, err))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("✅ %s ｜ 编号 %s ｜ 分块 %d", doc.Name, entry.ID, len(entry.Chunks)))
+	}
+	return buildKBCard("📚 添加文件到知识库", larkcard.TemplateGreen, strings.Join(lines, "\n"))
+}
+
+// kbTextSource 取文本开头作为来源名称
+func kbTextSource(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	runes := []rune(line)
+	if len(runes) > 20 {
+		return string(runes[:20]) + "…"
+	}
+	return line
+}
+
+func buildKBListCard(entries []*kb.Entry) *larkcard.MessageCard {
+	if len(entries) == 0 {
+		return buildKBCard("📚 知识库为空", larkcard.TemplateGrey, kbUsage)
+	}
+	var lines []string
+	for _, e := range entries {
+		lines = append(lines, fmt.Sprintf("`%s` **%s** ｜ 分块 %d ｜ %s", e.ID, e.Source, len(e.Chunks), e.CreatedAt.Format("2006-01-02 15:04")))
+	}
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader(fmt.Sprintf("📚 知识库（%d）", len(entries)), larkcard.TemplateBlue),
+		feishu.BuildCardMainMd(strings.Join(lines, "\n")),
+		feishu.BuildCardSplitLine(),
+		feishu.BuildCardNote("文本回复 */kb delete 编号* 删除条目"),
+	)
+}
+
+func buildKBCard(title, template, content string) *larkcard.MessageCard {
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader(title, template),
+		feishu.BuildCardMainMd(content),
+	)
+}
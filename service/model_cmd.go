@@ -0,0 +1,104 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/consts"
+	"ai-stream-bot/dal/cache"
+	"ai-stream-bot/model"
+	"ai-stream-bot/pkg/feishu"
+	"fmt"
+	"slices"
+	"strings"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// parseModelChoice 解析模型选择，支持 "provider"、"provider/model" 和 "provider model"
+func parseModelChoice(choice string) (string, string) {
+	choice = strings.TrimSpace(choice)
+	if idx := strings.IndexAny(choice, "/ "); idx >= 0 {
+		return choice[:idx], strings.TrimSpace(choice[idx+1:])
+	}
+	return choice, ""
+}
+
+// pinSessionModel 将服务提供商和模型固定到会话，模型需为服务配置的模型之一
+func pinSessionModel(sessionCache *cache.SessionCache, sessionId string, choice string) (*cache.SessionModel, error) {
+	provider, modelName := parseModelChoice(choice)
+	client, err := ai.GetManager().GetClient(provider)
+	if err != nil {
+		return nil, err
+	}
+	models := client.GetModels()
+	if modelName == "" && len(models) > 0 {
+		modelName = models[0]
+	}
+	if len(models) > 0 && !slices.Contains(models, modelName) {
+		return nil, fmt.Errorf("%s 不支持模型 %s，可选：%s", provider, modelName, strings.Join(models, "、"))
+	}
+	pinned := &cache.SessionModel{Provider: provider, Model: modelName}
+	sessionCache.SetModel(sessionId, pinned)
+	return pinned, nil
+}
+
+// buildModelListCard 构建模型列表卡片，每个模型对应一个切换按钮
+func buildModelListCard(chatType consts.ChatType, sessionId, msgId string, pinned *cache.SessionModel) *larkcard.MessageCard {
+	elements := []larkcard.MessageCardElement{
+		feishu.BuildCardMainMd("**选择本话题使用的模型**\n文本回复 */model 服务名/模型名* 也可以切换"),
+		feishu.BuildCardSplitLine(),
+	}
+	for _, info := range ai.GetManager().ListClients() {
+		for i, m := range info.Models {
+			text := fmt.Sprintf("**%s** / %s", info.Name, m)
+			if info.IsDefault && i == 0 {
+				text += "（默认）"
+			}
+			btnType := larkcard.MessageCardButtonTypeDefault
+			if pinned != nil && pinned.Provider == info.Name && pinned.Model == m {
+				text += " ✅"
+				btnType = larkcard.MessageCardButtonTypePrimary
+			}
+			elements = append(elements, feishu.BuildCardMdAndButton(text,
+				feishu.BuildEmbedButton("使用", map[string]interface{}{
+					"kind":      consts.ModelCard,
+					"chatType":  chatType,
+					"sessionId": sessionId,
+					"msgId":     msgId,
+					"value":     info.Name + "/" + m,
+				}, btnType),
+			))
+		}
+	}
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader("🤖 切换模型", larkcard.TemplateBlue),
+		elements...,
+	)
+}
+
+// buildModelPinnedCard 构建模型切换结果卡片
+func buildModelPinnedCard(pinned *cache.SessionModel, err error) *larkcard.MessageCard {
+	if err != nil {
+		return feishu.BuildMessageCard(
+			feishu.BuildCardHeader("🤖 切换模型失败", larkcard.TemplateRed),
+			feishu.BuildCardNote(err.Error()),
+			feishu.BuildCardNote("文本回复 */model* 查看可用模型"),
+		)
+	}
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader("🤖 已切换模型", larkcard.TemplateGreen),
+		feishu.BuildCardMainMd(fmt.Sprintf("本话题将使用 **%s** / %s", pinned.Provider, pinned.Model)),
+		feishu.BuildCardNote("在此话题内继续回复即可，文本回复 */clear* 可恢复默认"),
+	)
+}
+
+type ModelCardService struct {
+}
+
+func (s *ModelCardService) Execute(action *model.CardActionInfo) (*larkcard.MessageCard, bool) {
+	if action.Kind != consts.ModelCard {
+		return nil, true
+	}
+	choice, _ := action.Value.(string)
+	pinned, err := pinSessionModel(action.SessionCache, action.SessionId, choice)
+	return buildModelPinnedCard(pinned, err), true
+}
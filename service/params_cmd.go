@@ -0,0 +1,182 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/config"
+	"ai-stream-bot/model"
+	"ai-stream-bot/pkg"
+	"ai-stream-bot/pkg/feishu"
+	"fmt"
+	"strconv"
+	"strings"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+const paramsUsage = "*/params 参数=值* 设置本话题的生成参数，多个参数用空格分隔，stop 的多个值用 | 分隔\n*/params reset* 恢复配置中的参数"
+
+// paramNames /params 支持的参数，按卡片展示顺序排列
+var paramNames = []string{"temperature", "top_p", "max_tokens", "stop", "presence_penalty", "seed", "reasoning_effort"}
+
+// handleParamsCommand 处理 /params [参数=值 ...|reset]，arg 为命令之后的内容
+func handleParamsCommand(action *model.MsgActionInfo, arg string) *larkcard.MessageCard {
+	sessionId := *action.ActionMsgInfo.SessionId
+	arg = strings.TrimSpace(arg)
+
+	if _, ok := pkg.TrimEqual(arg, "reset"); ok {
+		action.SessionCache.ResetParams(sessionId)
+		return buildParamsCard(action, "⚙️ 已恢复默认参数", larkcard.TemplateGrey)
+	}
+	if arg == "" {
+		return buildParamsCard(action, "⚙️ 生成参数", larkcard.TemplateBlue)
+	}
+
+	override := config.GenerationConfig{}
+	if current := action.SessionCache.GetParams(sessionId); current != nil {
+		override = *current
+	}
+	if err := parseParams(&override, arg); err != nil {
+		return buildParamsErrorCard(err)
+	}
+	if err := override.Validate(); err != nil {
+		return buildParamsErrorCard(err)
+	}
+	action.SessionCache.SetParams(sessionId, &override)
+	return buildParamsCard(action, "⚙️ 已更新生成参数", larkcard.TemplateGreen)
+}
+
+// parseParams 解析空格分隔的 参数=值，写入 params
+func parseParams(params *config.GenerationConfig, arg string) error {
+	for _, field := range strings.Fields(arg) {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return fmt.Errorf("%s 格式错误，应为 参数=值", field)
+		}
+		name = strings.ToLower(name)
+		var err error
+		switch name {
+		case "temperature":
+			params.Temperature, err = parseFloatParam(name, value)
+		case "top_p":
+			params.TopP, err = parseFloatParam(name, value)
+		case "presence_penalty":
+			params.PresencePenalty, err = parseFloatParam(name, value)
+		case "max_tokens":
+			n, convErr := strconv.Atoi(value)
+			if convErr != nil || n <= 0 {
+				return fmt.Errorf("max_tokens 需为正整数")
+			}
+			params.MaxTokens = n
+		case "seed":
+			n, convErr := strconv.Atoi(value)
+			if convErr != nil {
+				return fmt.Errorf("seed 需为整数")
+			}
+			params.Seed = &n
+		case "stop":
+			// stop= 表示不使用配置中的 stop
+			params.Stop = []string{}
+			for _, s := range strings.Split(value, "|") {
+				if s != "" {
+					params.Stop = append(params.Stop, s)
+				}
+			}
+		case "reasoning_effort":
+			params.ReasoningEffort = strings.ToLower(value)
+		default:
+			return fmt.Errorf("不支持参数 %s，可选：%s", name, strings.Join(paramNames, "、"))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseFloatParam(name, value string) (*float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s 需为数字", name)
+	}
+	return &f, nil
+}
+
+// buildParamsCard 展示当前话题生效的参数，本话题设置的参数单独标注
+func buildParamsCard(action *model.MsgActionInfo, title, template string) *larkcard.MessageCard {
+	sessionId := *action.ActionMsgInfo.SessionId
+	provider, modelName := "", ""
+	if pinned := action.SessionCache.GetModel(sessionId); pinned != nil {
+		provider, modelName = pinned.Provider, pinned.Model
+	}
+	providerName := provider
+	if providerName == "" {
+		providerName = "默认服务"
+	}
+	aiManager := ai.GetManager()
+	override := action.SessionCache.GetParams(sessionId)
+	effective := aiManager.GetGeneration(provider).Merge(override)
+	if override == nil {
+		override = &config.GenerationConfig{}
+	}
+
+	values := map[string]string{
+		"temperature":      formatFloatParam(effective.Temperature),
+		"top_p":            formatFloatParam(effective.TopP),
+		"max_tokens":       strconv.Itoa(effective.MaxTokens),
+		"stop":             "默认",
+		"presence_penalty": formatFloatParam(effective.PresencePenalty),
+		"seed":             "默认",
+		"reasoning_effort": "默认",
+	}
+	if len(effective.Stop) > 0 {
+		values["stop"] = "`" + strings.Join(effective.Stop, "` | `") + "`"
+	} else if override.Stop != nil {
+		values["stop"] = "无"
+	}
+	if effective.Seed != nil {
+		values["seed"] = strconv.Itoa(*effective.Seed)
+	}
+	if effective.ReasoningEffort != "" {
+		values["reasoning_effort"] = effective.ReasoningEffort
+	}
+	overridden := map[string]bool{
+		"temperature":      override.Temperature != nil,
+		"top_p":            override.TopP != nil,
+		"max_tokens":       override.MaxTokens > 0,
+		"stop":             override.Stop != nil,
+		"presence_penalty": override.PresencePenalty != nil,
+		"seed":             override.Seed != nil,
+		"reasoning_effort": override.ReasoningEffort != "",
+	}
+
+	lines := make([]string, 0, len(paramNames))
+	for _, name := range paramNames {
+		line := fmt.Sprintf("**%s**：%s", name, values[name])
+		if overridden[name] {
+			line += "（本话题）"
+		}
+		lines = append(lines, line)
+	}
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader(title, template),
+		feishu.BuildCardMainMd(fmt.Sprintf("当前模型：**%s** / %s", providerName, aiManager.GetModel(provider, modelName))),
+		feishu.BuildCardMainMd(strings.Join(lines, "\n")),
+		feishu.BuildCardSplitLine(),
+		feishu.BuildCardNote(paramsUsage),
+	)
+}
+
+func buildParamsErrorCard(err error) *larkcard.MessageCard {
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader("⚙️ 设置参数失败", larkcard.TemplateRed),
+		feishu.BuildCardMainMd(err.Error()),
+		feishu.BuildCardNote(paramsUsage),
+	)
+}
+
+func formatFloatParam(v *float64) string {
+	if v == nil {
+		return "默认"
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"ai-stream-bot/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestParseParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		current config.GenerationConfig
+		arg     string
+		want    config.GenerationConfig
+		wantErr string
+	}{
+		{
+			name: "all params",
+			arg:  "temperature=0.7 TOP_P=0.9 max_tokens=1024 stop=###|END presence_penalty=-0.5 seed=42 reasoning_effort=HIGH",
+			want: config.GenerationConfig{
+				Temperature:     floatPtr(0.7),
+				TopP:            floatPtr(0.9),
+				MaxTokens:       1024,
+				Stop:            []string{"###", "END"},
+				PresencePenalty: floatPtr(-0.5),
+				Seed:            intPtr(42),
+				ReasoningEffort: "high",
+			},
+		},
+		{
+			name:    "keeps current params",
+			current: config.GenerationConfig{Temperature: floatPtr(1), MaxTokens: 512},
+			arg:     "top_p=0.5",
+			want:    config.GenerationConfig{Temperature: floatPtr(1), TopP: floatPtr(0.5), MaxTokens: 512},
+		},
+		{name: "empty stop clears configured stop", arg: "stop=", want: config.GenerationConfig{Stop: []string{}}},
+		{name: "missing equal sign", arg: "temperature", wantErr: "格式错误"},
+		{name: "unknown param", arg: "top_k=5", wantErr: "不支持参数 top_k"},
+		{name: "float not a number", arg: "temperature=hot", wantErr: "temperature 需为数字"},
+		{name: "max tokens not positive", arg: "max_tokens=0", wantErr: "max_tokens 需为正整数"},
+		{name: "seed not an integer", arg: "seed=1.5", wantErr: "seed 需为整数"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := tt.current
+			err := parseParams(&params, tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseParams() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseParams() error = %v", err)
+			}
+			if !reflect.DeepEqual(params, tt.want) {
+				t.Errorf("parseParams() = %+v, want %+v", params, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"ai-stream-bot/client/im"
+	"ai-stream-bot/config"
+	"ai-stream-bot/consts"
+	"ai-stream-bot/model"
+	"ai-stream-bot/pkg"
+	"ai-stream-bot/pkg/feishu"
+	"strings"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// systemPrompt 获取当前会话的 system prompt，/persona 设置的优先于配置
+func systemPrompt(action *model.MsgActionInfo) string {
+	chatId := ""
+	if action.ActionMsgInfo.ChatId != nil {
+		chatId = *action.ActionMsgInfo.ChatId
+	}
+	if action.PersonaCache != nil {
+		if prompt := action.PersonaCache.GetPersona(chatId); prompt != "" {
+			return prompt
+		}
+	}
+	return config.GetSystemPrompt(chatId, string(action.ActionMsgInfo.ChatType))
+}
+
+// handlePersonaCommand 处理 /persona set|show|reset，arg 为命令之后的内容
+func handlePersonaCommand(action *model.MsgActionInfo, arg string) *larkcard.MessageCard {
+	chatId := *action.ActionMsgInfo.ChatId
+	arg = strings.TrimSpace(arg)
+
+	_, isSet := pkg.CutPrefix(arg, "set")
+	_, isReset := pkg.TrimEqual(arg, "reset")
+	if (isSet || isReset) && !canEditPersona(action, chatId) {
+		return feishu.BuildMessageCard(
+			feishu.BuildCardHeader("🎭 无权修改人设", larkcard.TemplateRed),
+			feishu.BuildCardNote("只有群主或配置中的管理员可以修改群聊人设，文本回复 */persona show* 查看当前人设"),
+		)
+	}
+
+	if prompt, ok := pkg.CutPrefix(arg, "set"); ok {
+		prompt = strings.TrimSpace(prompt)
+		if prompt == "" {
+			return feishu.BuildMessageCard(
+				feishu.BuildCardHeader("🎭 设置人设失败", larkcard.TemplateRed),
+				feishu.BuildCardNote("人设内容不能为空，文本回复 */persona set 你的设定*"),
+			)
+		}
+		if err := action.PersonaCache.SetPersona(chatId, prompt); err != nil {
+			hlog.Errorf("save persona error: %v", err)
+			return buildPersonaErrorCard("🎭 设置人设失败")
+		}
+		return feishu.BuildMessageCard(
+			feishu.BuildCardHeader("🎭 已设置人设", larkcard.TemplateGreen),
+			feishu.BuildCardMainMd(prompt),
+			feishu.BuildCardNote("此群聊中的所有对话都会使用该人设，文本回复 */persona reset* 恢复默认"),
+		)
+	}
+	if _, ok := pkg.TrimEqual(arg, "reset"); ok {
+		if err := action.PersonaCache.Reset(chatId); err != nil {
+			hlog.Errorf("save persona error: %v", err)
+			return buildPersonaErrorCard("🎭 恢复默认人设失败")
+		}
+		return feishu.BuildMessageCard(
+			feishu.BuildCardHeader("🎭 已恢复默认人设", larkcard.TemplateGrey),
+			feishu.BuildCardNote("此群聊将使用配置中的默认 system prompt"),
+		)
+	}
+
+	prompt := systemPrompt(action)
+	if prompt == "" {
+		prompt = "未设置"
+	}
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader("🎭 当前人设", larkcard.TemplateBlue),
+		feishu.BuildCardMainMd(prompt),
+		feishu.BuildCardSplitLine(),
+		feishu.BuildCardNote("*/persona set 设定* 设置人设，*/persona reset* 恢复默认"),
+	)
+}
+
+// canEditPersona 单聊中用户可修改自己的人设，群聊中只有群主或配置中的管理员可以修改
+func canEditPersona(action *model.MsgActionInfo, chatId string) bool {
+	userId := action.ActionMsgInfo.UserId
+	if action.ActionMsgInfo.ChatType != consts.GroupChatType || config.IsPromptAdmin(userId) {
+		return true
+	}
+	owner, err := im.GetFeishuClient().FeishuGetChatOwner(action.Ctx, chatId)
+	if err != nil {
+		hlog.Errorf("get chat owner error: %v", err)
+		return false
+	}
+	return owner != "" && owner == userId
+}
+
+func buildPersonaErrorCard(title string) *larkcard.MessageCard {
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader(title, larkcard.TemplateRed),
+		feishu.BuildCardNote("保存人设失败，请稍后重试"),
+	)
+}
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// referenceMarker 回答中的引用标注，如 [1]；提示词中的来源使用单独的编号空间，标注为 [资料1]
+var referenceMarker = regexp.MustCompile(`\[(` + promptMarkerPrefix + `)?(\d{1,3})\]`)
+
+// promptMarkerPrefix 提示词中来源标注的前缀，与模型服务返回的引用标注区分
+const promptMarkerPrefix = "资料"
+
+// referenceList 按首次出现的顺序编号的引用，同一地址只保留一条
+// 提示词中已编号的来源（如知识库）排在最前，模型服务返回的引用依次编在其后，两者在回答中的标注互不冲突
+type referenceList struct {
+	refs  []ai.Reference
+	index map[string]int
+	// prompt 提示词中已编号的来源数量
+	prompt int
+}
+
+func newReferenceList() *referenceList {
+	return &referenceList{index: map[string]int{}}
+}
+
+// Add 添加引用，已存在时忽略
+func (l *referenceList) Add(ref ai.Reference) {
+	key := ref.Key()
+	if key == "" {
+		return
+	}
+	if _, ok := l.index[key]; ok {
+		return
+	}
+	l.refs = append(l.refs, ref)
+	l.index[key] = len(l.refs)
+}
+
+// AddPrompt 添加提示词中已编号的来源，需在模型服务返回引用之前添加，顺序与提示词中的 [资料n] 一致
+func (l *referenceList) AddPrompt(ref ai.Reference) {
+	l.Add(ref)
+	l.prompt = len(l.refs)
+}
+
+// Footnotes 渲染为编号脚注，编号与回答中的 [n] 对应
+func (l *referenceList) Footnotes() string {
+	var footnotes strings.Builder
+	for i, ref := range l.refs {
+		title := ref.Title
+		if title == "" {
+			title = ref.Site
+		}
+		if title == "" {
+			title = ref.URL
+		}
+		// 标题中的方括号会破坏链接语法
+		title = strings.NewReplacer("[", "【", "]", "】").Replace(title)
+		line := fmt.Sprintf("[%d] %s", i+1, title)
+		if ref.URL != "" {
+			line = fmt.Sprintf("[%d] [%s](%s)", i+1, title, ref.URL)
+		}
+		if ref.Site != "" && ref.Site != title {
+			line += " · " + ref.Site
+		}
+		footnotes.WriteString(line + "\n")
+	}
+	return footnotes.String()
+}
+
+// LinkMarkers 将回答中的标注替换为脚注编号，有地址时链接到对应的资料，编号不存在时保持原样
+// [资料n] 为提示词中的第 n 个来源；[n] 为模型服务返回的第 n 个引用（如火山联网搜索），依次编在提示词来源之后，
+// 模型服务没有返回引用时 [n] 按提示词中的来源处理
+func (l *referenceList) LinkMarkers(answer string) string {
+	if len(l.refs) == 0 {
+		return answer
+	}
+	var linked strings.Builder
+	last := 0
+	for _, m := range referenceMarker.FindAllStringSubmatchIndex(answer, -1) {
+		start, end := m[0], m[1]
+		// 已经是链接的标注不再处理
+		if end < len(answer) && answer[end] == '(' || start > 0 && answer[start-1] == '[' {
+			continue
+		}
+		n, _ := strconv.Atoi(answer[m[4]:m[5]])
+		fromPrompt := m[2] >= 0
+		if n < 1 || fromPrompt && n > l.prompt {
+			continue
+		}
+		if !fromPrompt && len(l.refs) > l.prompt {
+			n += l.prompt
+		}
+		if n > len(l.refs) {
+			continue
+		}
+		linked.WriteString(answer[last:start])
+		if url := l.refs[n-1].URL; url != "" {
+			linked.WriteString(fmt.Sprintf("[[%d]](%s)", n, url))
+		} else {
+			linked.WriteString(fmt.Sprintf("[%d]", n))
+		}
+		last = end
+	}
+	linked.WriteString(answer[last:])
+	return linked.String()
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"testing"
+)
+
+func TestReferenceListFootnotes(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt []ai.Reference
+		refs   []ai.Reference
+		want   string
+	}{
+		{name: "empty", want: ""},
+		{
+			name: "dedupe by url",
+			refs: []ai.Reference{
+				{Title: "Go 1.23", URL: "https://go.dev/doc/go1.23", Site: "go.dev"},
+				{Title: "Go 1.23 重复", URL: "https://go.dev/doc/go1.23"},
+				{Title: "Hertz", URL: "https://www.cloudwego.io/"},
+			},
+			want: "[1] [Go 1.23](https://go.dev/doc/go1.23) · go.dev\n[2] [Hertz](https://www.cloudwego.io/)\n",
+		},
+		{
+			name: "title fallback and brackets",
+			refs: []ai.Reference{
+				{Site: "example.com", URL: "https://example.com/a"},
+				{URL: "https://example.com/b"},
+				{Title: "[公告] 新版本", URL: "https://example.com/c"},
+			},
+			want: "[1] [example.com](https://example.com/a)\n[2] [https://example.com/b](https://example.com/b)\n[3] [【公告】 新版本](https://example.com/c)\n",
+		},
+		{
+			name:   "prompt sources first",
+			prompt: []ai.Reference{{Title: "部署文档.pdf"}, {Title: "部署文档.pdf"}},
+			refs:   []ai.Reference{{Title: "搜索结果", URL: "https://example.com"}},
+			want:   "[1] 部署文档.pdf\n[2] [搜索结果](https://example.com)\n",
+		},
+		{name: "reference without key is ignored", refs: []ai.Reference{{Snippet: "只有摘要"}}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newReferenceList()
+			for _, ref := range tt.prompt {
+				l.AddPrompt(ref)
+			}
+			for _, ref := range tt.refs {
+				l.Add(ref)
+			}
+			if got := l.Footnotes(); got != tt.want {
+				t.Errorf("Footnotes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReferenceListLinkMarkers(t *testing.T) {
+	web := []ai.Reference{
+		{Title: "a", URL: "https://a.com"},
+		{Title: "b", URL: "https://b.com"},
+	}
+	tests := []struct {
+		name   string
+		prompt []ai.Reference
+		refs   []ai.Reference
+		answer string
+		want   string
+	}{
+		{name: "no references", answer: "见 [1]", want: "见 [1]"},
+		{name: "link markers", refs: web, answer: "见 [1] 和 [2]。", want: "见 [[1]](https://a.com) 和 [[2]](https://b.com)。"},
+		{name: "out of range and zero", refs: web, answer: "[0] [3] [1]", want: "[0] [3] [[1]](https://a.com)"},
+		{name: "already linked", refs: web, answer: "[[1]](https://a.com) [1](https://x.com)", want: "[[1]](https://a.com) [1](https://x.com)"},
+		{name: "reference without url", refs: []ai.Reference{{Title: "文件"}}, answer: "见 [1]", want: "见 [1]"},
+		{
+			name:   "provider markers follow prompt sources",
+			prompt: []ai.Reference{{Title: "部署文档.pdf"}},
+			refs:   web,
+			answer: "见 [1] 和 [2]",
+			want:   "见 [[2]](https://a.com) 和 [[3]](https://b.com)",
+		},
+		{
+			name:   "prompt sources only keep numbering",
+			prompt: []ai.Reference{{Title: "部署文档.pdf"}, {Title: "wiki", URL: "https://wiki.com"}},
+			answer: "见 [1] 和 [2]",
+			want:   "见 [1] 和 [[2]](https://wiki.com)",
+		},
+		{
+			name:   "prompt markers without provider references",
+			prompt: []ai.Reference{{Title: "部署文档.pdf"}, {Title: "wiki", URL: "https://wiki.com"}},
+			answer: "见 [资料1] 和 [资料2]，[资料3] 不存在",
+			want:   "见 [1] 和 [[2]](https://wiki.com)，[资料3] 不存在",
+		},
+		{
+			name:   "prompt and provider markers mixed",
+			prompt: []ai.Reference{{Title: "部署文档.pdf"}, {Title: "wiki", URL: "https://wiki.com"}},
+			refs:   append(web, ai.Reference{Title: "文档引用"}),
+			answer: "部署见 [资料1]，说明见 [资料2]，搜索结果见 [1] 和 [2]，另见 [3]，[4] 不存在",
+			want:   "部署见 [1]，说明见 [[2]](https://wiki.com)，搜索结果见 [[3]](https://a.com) 和 [[4]](https://b.com)，另见 [5]，[4] 不存在",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newReferenceList()
+			for _, ref := range tt.prompt {
+				l.AddPrompt(ref)
+			}
+			for _, ref := range tt.refs {
+				l.Add(ref)
+			}
+			if got := l.LinkMarkers(tt.answer); got != tt.want {
+				t.Errorf("LinkMarkers() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/client/im"
+	"ai-stream-bot/consts"
+	"ai-stream-bot/dal/cache"
+	"ai-stream-bot/model"
+	"ai-stream-bot/pkg/feishu"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"slices"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+const (
+	// replyButtonsElementId 回答结束后追加的按钮行
+	replyButtonsElementId = "reply_actions"
+	// continuePrompt 继续生成时追加的用户消息，不写入会话历史
+	continuePrompt = "你的上一条回答因长度限制被截断，请紧接着中断的位置继续输出，不要重复已输出的内容，也不要添加开场白。"
+	busyNotice     = "当前话题正在生成回答，请等待回答结束或停止生成后再试"
+	staleNotice    = "该回答已被重新生成、续写或已有新的对话，只能操作话题中最新的回答"
+)
+
+// replyTurn 回答按钮对应的回答，按钮只对话题中最新的回答生效
+type replyTurn struct {
+	// Index 回答在会话消息中的下标
+	Index int
+	// Digest 回答内容的摘要，回答被重新生成或续写后不再匹配
+	Digest string
+}
+
+// latestReplyTurn 会话中最后一条回答，需在回答写入会话后调用
+func latestReplyTurn(msgs []ai.AiMessage) replyTurn {
+	last := len(msgs) - 1
+	if last < 0 || msgs[last].Role != "assistant" {
+		return replyTurn{Index: -1}
+	}
+	return replyTurn{Index: last, Digest: answerDigest(msgs[last].Content)}
+}
+
+// Matches 按钮对应的回答是否仍是会话中最新的回答
+func (t *replyTurn) Matches(msgs []ai.AiMessage) bool {
+	return t.Index >= 0 && t.Index == len(msgs)-1 && latestReplyTurn(msgs) == *t
+}
+
+func answerDigest(answer string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(answer))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// appendReplyButtons 在回答结束的卡片上追加重新生成按钮，因长度截断时追加继续按钮，需在回答写入会话后调用
+func appendReplyButtons(action *model.MsgActionInfo, plan *replyPlan, cardId string, finishReason string) {
+	chatId := ""
+	if action.ActionMsgInfo.ChatId != nil {
+		chatId = *action.ActionMsgInfo.ChatId
+	}
+	turn := latestReplyTurn(action.SessionCache.GetMsg(*action.ActionMsgInfo.SessionId))
+	value := func(kind consts.CardKind) map[string]interface{} {
+		return map[string]interface{}{
+			"kind":      kind,
+			"chatType":  action.ActionMsgInfo.ChatType,
+			"chatId":    chatId,
+			"sessionId": *action.ActionMsgInfo.SessionId,
+			"msgId":     *action.ActionMsgInfo.MsgId,
+			"value":     modelChoice(plan.Provider, plan.Model),
+			"turn":      turn.Index,
+			"digest":    turn.Digest,
+		}
+	}
+	buttons := []map[string]interface{}{
+		feishu.BuildStreamCardButton("regenerate", "🔄 重新生成", "default", value(consts.RegenerateCard)),
+	}
+	if finishReason == ai.FinishReasonLength {
+		buttons = append(buttons, feishu.BuildStreamCardButton("continue", "➡️ 继续生成", "primary", value(consts.ContinueCard)))
+	}
+	elements, _ := json.Marshal([]map[string]interface{}{
+		feishu.BuildStreamCardButtonRow(replyButtonsElementId, buttons...),
+	})
+	if err := im.GetFeishuClient().FeishuAppendCardElements(action.Ctx, cardId, string(elements)); err != nil {
+		hlog.Errorf("append reply buttons error: %v", err)
+	}
+}
+
+// modelChoice 拼接为 parseModelChoice 可解析的格式
+func modelChoice(provider, modelName string) string {
+	if provider == "" || modelName == "" {
+		return provider
+	}
+	return provider + "/" + modelName
+}
+
+// resolveChoice 解析指定的模型，未指定时使用会话固定的模型
+func resolveChoice(action *model.MsgActionInfo, choice string) (string, string) {
+	if choice != "" {
+		return parseModelChoice(choice)
+	}
+	if pinned := action.SessionCache.GetModel(*action.ActionMsgInfo.SessionId); pinned != nil {
+		return pinned.Provider, pinned.Model
+	}
+	return "", ""
+}
+
+// Regenerate 重新回答会话中最后一条用户消息，新回答替换原有的回答，choice 可指定其它模型
+// turn 不为空时只在其仍是最新的回答时生效
+func (s *FeishuMsgService) Regenerate(action *model.MsgActionInfo, choice string, turn *replyTurn) {
+	sessionId := *action.ActionMsgInfo.SessionId
+	if !GetGenerationRegistry().Reserve(sessionId) {
+		replyNotice(action, "🔄 无法重新生成", busyNotice)
+		return
+	}
+	defer GetGenerationRegistry().Release(sessionId)
+	msgs := action.SessionCache.GetMsg(sessionId)
+	if turn != nil && !turn.Matches(msgs) {
+		replyNotice(action, "🔄 无法重新生成", staleNotice)
+		return
+	}
+	last := lastUserIndex(msgs)
+	if last < 0 {
+		replyNotice(action, "🔄 无法重新生成", "当前话题中没有可以重新生成的对话")
+		return
+	}
+	history := slices.Clone(msgs[:last+1])
+	provider, modelName := resolveChoice(action, choice)
+	s.streamReply(action, &replyPlan{
+		Provider: provider,
+		Model:    modelName,
+		Msgs:     history,
+		Query:    history[last].Content,
+		Save: func(answer string) []ai.AiMessage {
+			return append(history, ai.AiMessage{Role: "assistant", Content: answer})
+		},
+	})
+}
+
+// Continue 让模型接着被截断的回答继续输出，续写内容合并到最后一条回答
+// turn 不为空时只在其仍是最新的回答时生效
+func (s *FeishuMsgService) Continue(action *model.MsgActionInfo, choice string, turn *replyTurn) {
+	sessionId := *action.ActionMsgInfo.SessionId
+	if !GetGenerationRegistry().Reserve(sessionId) {
+		replyNotice(action, "➡️ 无法继续生成", busyNotice)
+		return
+	}
+	defer GetGenerationRegistry().Release(sessionId)
+	msgs := action.SessionCache.GetMsg(sessionId)
+	if turn != nil && !turn.Matches(msgs) {
+		replyNotice(action, "➡️ 无法继续生成", staleNotice)
+		return
+	}
+	last := lastUserIndex(msgs)
+	if last < 0 || msgs[len(msgs)-1].Role != "assistant" {
+		replyNotice(action, "➡️ 无法继续生成", "当前话题的最后一条消息不是回答")
+		return
+	}
+	history := slices.Clone(msgs)
+	provider, modelName := resolveChoice(action, choice)
+	s.streamReply(action, &replyPlan{
+		Provider: provider,
+		Model:    modelName,
+		Msgs:     append(slices.Clone(history), ai.AiMessage{Role: "user", Content: continuePrompt}),
+		Query:    history[last].Content,
+		Save: func(answer string) []ai.AiMessage {
+			history[len(history)-1].Content += answer
+			return history
+		},
+	})
+}
+
+func lastUserIndex(msgs []ai.AiMessage) int {
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == "user" {
+			return i
+		}
+	}
+	return -1
+}
+
+func replyNotice(action *model.MsgActionInfo, title, note string) {
+	card := feishu.BuildMessageCard(
+		feishu.BuildCardHeader(title, larkcard.TemplateGrey),
+		feishu.BuildCardNote(note),
+	)
+	cardStr, _ := card.String()
+	im.GetFeishuClient().FeishuReplyMsg(action.Ctx, *action.ActionMsgInfo.MsgId, cardStr)
+}
+
+// ReplyCardService 处理回答卡片上的重新生成和继续生成按钮
+type ReplyCardService struct {
+}
+
+func (s *ReplyCardService) Execute(action *model.CardActionInfo) (*larkcard.MessageCard, bool) {
+	if action.Kind != consts.RegenerateCard && action.Kind != consts.ContinueCard {
+		return nil, true
+	}
+	msgAction := msgActionFromCard(action)
+	choice, _ := action.Value.(string)
+	turn := &replyTurn{Index: action.Turn, Digest: action.Digest}
+	msgService := NewFeishuMsgService(ai.GetManager())
+	// 回调需在 3 秒内响应，生成在后台进行
+	if action.Kind == consts.RegenerateCard {
+		go msgService.Regenerate(msgAction, choice, turn)
+		action.Toast = "正在重新生成"
+	} else {
+		go msgService.Continue(msgAction, choice, turn)
+		action.Toast = "正在继续生成"
+	}
+	return nil, true
+}
+
+// msgActionFromCard 根据按钮携带的会话信息构造消息处理的上下文
+func msgActionFromCard(action *model.CardActionInfo) *model.MsgActionInfo {
+	msgId, chatId, sessionId := action.MsgId, action.ChatId, action.SessionId
+	return &model.MsgActionInfo{
+		// 回调的 ctx 在响应后结束，生成使用独立的 ctx
+		Ctx: context.Background(),
+		ActionMsgInfo: &model.ActionMsgInfo{
+			ChatType:  action.ChatType,
+			MsgId:     &msgId,
+			ChatId:    &chatId,
+			UserId:    action.UserId,
+			SessionId: &sessionId,
+		},
+		MsgCache:     cache.GetMsgCache(),
+		SessionCache: action.SessionCache,
+		UsageCache:   cache.GetUsageCache(),
+		PersonaCache: cache.GetPersonaCache(),
+	}
+}
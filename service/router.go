@@ -0,0 +1,44 @@
+package service
+
+import (
+	"ai-stream-bot/config"
+	"ai-stream-bot/model"
+	"slices"
+	"strings"
+)
+
+// ModelRouter 根据路由规则为消息选择服务提供商和模型
+type ModelRouter struct {
+	rules []*config.RouteRule
+}
+
+func NewModelRouter(rules []*config.RouteRule) *ModelRouter {
+	return &ModelRouter{rules: rules}
+}
+
+// Route 返回第一条命中的规则，未命中时返回 nil
+func (r *ModelRouter) Route(info *model.ActionMsgInfo) *config.RouteRule {
+	for _, rule := range r.rules {
+		if matchRoute(rule, info) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// matchRoute 判断消息是否满足规则中配置的全部条件
+func matchRoute(rule *config.RouteRule, info *model.ActionMsgInfo) bool {
+	if len(rule.ChatIds) > 0 && (info.ChatId == nil || !slices.Contains(rule.ChatIds, *info.ChatId)) {
+		return false
+	}
+	if len(rule.UserIds) > 0 && !slices.Contains(rule.UserIds, info.UserId) {
+		return false
+	}
+	if rule.ChatType != "" && rule.ChatType != string(info.ChatType) {
+		return false
+	}
+	if rule.Prefix != "" && !strings.HasPrefix(info.Content, rule.Prefix) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"ai-stream-bot/config"
+	"ai-stream-bot/consts"
+	"ai-stream-bot/model"
+	"testing"
+)
+
+func TestMatchRoute(t *testing.T) {
+	chatId := "oc_eng"
+	group := &model.ActionMsgInfo{ChatType: consts.GroupChatType, ChatId: &chatId, UserId: "u1", Content: "#code 写个排序"}
+	p2p := &model.ActionMsgInfo{ChatType: consts.UserChatType, UserId: "u2", Content: "你好"}
+	tests := []struct {
+		name string
+		rule *config.RouteRule
+		info *model.ActionMsgInfo
+		want bool
+	}{
+		{name: "empty rule matches all", rule: &config.RouteRule{}, info: p2p, want: true},
+		{name: "chat id", rule: &config.RouteRule{ChatIds: []string{"oc_x", "oc_eng"}}, info: group, want: true},
+		{name: "chat id mismatch", rule: &config.RouteRule{ChatIds: []string{"oc_x"}}, info: group, want: false},
+		{name: "chat id without chat", rule: &config.RouteRule{ChatIds: []string{"oc_eng"}}, info: p2p, want: false},
+		{name: "user id", rule: &config.RouteRule{UserIds: []string{"u1"}}, info: group, want: true},
+		{name: "user id mismatch", rule: &config.RouteRule{UserIds: []string{"u1"}}, info: p2p, want: false},
+		{name: "chat type", rule: &config.RouteRule{ChatType: "personal"}, info: p2p, want: true},
+		{name: "chat type mismatch", rule: &config.RouteRule{ChatType: "group"}, info: p2p, want: false},
+		{name: "prefix", rule: &config.RouteRule{Prefix: "#code"}, info: group, want: true},
+		{name: "prefix not at start", rule: &config.RouteRule{Prefix: "排序"}, info: group, want: false},
+		{name: "all conditions", rule: &config.RouteRule{ChatIds: []string{"oc_eng"}, UserIds: []string{"u1"}, ChatType: "group", Prefix: "#code"}, info: group, want: true},
+		{name: "one condition fails", rule: &config.RouteRule{ChatIds: []string{"oc_eng"}, UserIds: []string{"u2"}, Prefix: "#code"}, info: group, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchRoute(tt.rule, tt.info); got != tt.want {
+				t.Errorf("matchRoute() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModelRouterFirstMatch(t *testing.T) {
+	router := NewModelRouter([]*config.RouteRule{
+		{Name: "code", Prefix: "#code"},
+		{Name: "p2p", ChatType: "personal"},
+		{Name: "fallback"},
+	})
+	tests := []struct {
+		content  string
+		chatType consts.ChatType
+		want     string
+	}{
+		{content: "#code 写个排序", chatType: consts.UserChatType, want: "code"},
+		{content: "你好", chatType: consts.UserChatType, want: "p2p"},
+		{content: "你好", chatType: consts.GroupChatType, want: "fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			rule := router.Route(&model.ActionMsgInfo{ChatType: tt.chatType, Content: tt.content})
+			if rule == nil || rule.Name != tt.want {
+				t.Errorf("Route() = %+v, want %s", rule, tt.want)
+			}
+		})
+	}
+	if rule := NewModelRouter(nil).Route(&model.ActionMsgInfo{}); rule != nil {
+		t.Errorf("Route() without rules = %+v, want nil", rule)
+	}
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/config"
+	"ai-stream-bot/dal/cache"
+	"ai-stream-bot/pkg"
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudwego/hertz/pkg/common/hlog"
+)
+
+const (
+	// summaryPrefix 摘要 system 消息的前缀，用于识别并替换旧摘要
+	summaryPrefix       = "【之前对话的摘要】\n"
+	summaryDefaultTurns = 4
+	summaryTimeout      = 2 * time.Minute
+	summaryInstruction  = "你是对话摘要助手。请用简洁的中文总结以下对话，保留关键事实、已达成的结论和决定、待办事项以及用户的偏好，不要编造内容，不要输出与摘要无关的话。"
+)
+
+// summarizing 正在生成摘要的会话，避免同一会话并发摘要
+var summarizing sync.Map
+
+// HistorySummarizer 将会话中较早的对话压缩为一条 system 摘要消息
+type HistorySummarizer struct {
+	aiManager *ai.Manager
+	cfg       *config.SummaryConfig
+}
+
+func NewHistorySummarizer(aiManager *ai.Manager, cfg *config.SummaryConfig) *HistorySummarizer {
+	return &HistorySummarizer{aiManager: aiManager, cfg: cfg}
+}
+
+// MaybeSummarize 历史消息超过阈值时异步生成摘要并写回会话缓存
+func (h *HistorySummarizer) MaybeSummarize(sessionCache *cache.SessionCache, sessionId string) {
+	if h.cfg == nil || h.cfg.Threshold <= 0 {
+		return
+	}
+	msgs := sessionCache.GetMsg(sessionId)
+	if pkg.EstimateMessagesTokens(msgs) <= h.cfg.Threshold {
+		return
+	}
+	if _, loaded := summarizing.LoadOrStore(sessionId, struct{}{}); loaded {
+		return
+	}
+	go func() {
+		defer summarizing.Delete(sessionId)
+		ctx, cancel := context.WithTimeout(context.Background(), summaryTimeout)
+		defer cancel()
+		if err := h.summarize(ctx, sessionCache, sessionId, msgs); err != nil {
+			hlog.Errorf("summarize session %s error: %v", sessionId, err)
+		}
+	}()
+}
+
+// summarize 摘要 snapshot 中除最近 KeepTurns 轮以外的对话
+func (h *HistorySummarizer) summarize(ctx context.Context, sessionCache *cache.SessionCache, sessionId string, snapshot []ai.AiMessage) error {
+	keepTurns := h.cfg.KeepTurns
+	if keepTurns <= 0 {
+		keepTurns = summaryDefaultTurns
+	}
+	systems, turns := pkg.SplitTurns(snapshot)
+	if len(turns) <= keepTurns {
+		return nil
+	}
+	older := turns[:len(turns)-keepTurns]
+
+	var transcript strings.Builder
+	var others []ai.AiMessage
+	for _, m := range systems {
+		if previous, ok := strings.CutPrefix(m.Content, summaryPrefix); ok {
+			transcript.WriteString("此前的摘要：\n" + previous + "\n\n")
+			continue
+		}
+		others = append(others, m)
+	}
+	for _, turn := range older {
+		for _, m := range turn {
+			transcript.WriteString(fmt.Sprintf("%s: %s\n", m.Role, m.Content))
+		}
+	}
+
+	summary, err := collectChat(ctx, h.aiManager, h.cfg.Provider, &ai.AiChatStreamRequest{
+		Model: h.cfg.Model,
+		Msgs: []ai.AiMessage{
+			{Role: "system", Content: summaryInstruction},
+			{Role: "user", Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(summary) == "" {
+		return fmt.Errorf("empty summary")
+	}
+
+	// 摘要期间会话可能有新消息，只替换已摘要的部分，比较与写入在同一次更新中完成
+	var msgs []ai.AiMessage
+	err = sessionCache.UpdateMsg(sessionId, func(current []ai.AiMessage) ([]ai.AiMessage, error) {
+		if len(current) < len(snapshot) || !reflect.DeepEqual(current[:len(snapshot)], snapshot) {
+			return nil, fmt.Errorf("session changed during summarization")
+		}
+		msgs = append(others, ai.AiMessage{Role: "system", Content: summaryPrefix + strings.TrimSpace(summary)})
+		for _, turn := range turns[len(turns)-keepTurns:] {
+			msgs = append(msgs, turn...)
+		}
+		msgs = append(msgs, current[len(snapshot):]...)
+		return msgs, nil
+	})
+	if err != nil {
+		return err
+	}
+	hlog.Infof("session %s summarized %d turns, tokens %d -> %d", sessionId, len(older),
+		pkg.EstimateMessagesTokens(snapshot), pkg.EstimateMessagesTokens(msgs))
+	return nil
+}
+
+// collectChat 发送非交互的聊天请求并返回完整回答，思考和引用内容会被丢弃
+func collectChat(ctx context.Context, aiManager *ai.Manager, provider string, req *ai.AiChatStreamRequest) (string, error) {
+	var answer strings.Builder
+	var err error
+	for event := range aiManager.StreamEvents(ctx, provider, req) {
+		switch event.Kind {
+		case ai.EventAnswerDelta:
+			answer.WriteString(event.Text)
+		case ai.EventError:
+			err = event.Err
+		}
+	}
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return answer.String(), err
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"ai-stream-bot/consts"
+	"ai-stream-bot/dal/cache"
+	"ai-stream-bot/model"
+	"ai-stream-bot/pkg/feishu"
+	"fmt"
+	"strings"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// handleUsageCommand 处理 /usage，展示当前用户和群聊累计的 token 用量
+func handleUsageCommand(action *model.MsgActionInfo) *larkcard.MessageCard {
+	if action.UsageCache == nil {
+		return feishu.BuildMessageCard(
+			feishu.BuildCardHeader("📊 Token 用量", larkcard.TemplateGrey),
+			feishu.BuildCardNote("未开启用量统计"),
+		)
+	}
+	elements := []larkcard.MessageCardElement{
+		feishu.BuildCardMainMd("**我的用量**\n" + formatUsageStat(action.UsageCache.GetUserUsage(action.ActionMsgInfo.UserId))),
+	}
+	if action.ActionMsgInfo.ChatType == consts.GroupChatType && action.ActionMsgInfo.ChatId != nil {
+		elements = append(elements,
+			feishu.BuildCardSplitLine(),
+			feishu.BuildCardMainMd("**本群用量**\n"+formatUsageStat(action.UsageCache.GetChatUsage(*action.ActionMsgInfo.ChatId))),
+		)
+	}
+	elements = append(elements, feishu.BuildCardNote("统计自服务启动以来的请求；模型服务未返回用量时（如停止生成、超时）按内容估算，单独列出"))
+	return feishu.BuildMessageCard(feishu.BuildCardHeader("📊 Token 用量", larkcard.TemplateBlue), elements...)
+}
+
+// formatUsageStat 渲染汇总的用量，估算的用量单独一行
+func formatUsageStat(stat cache.UsageStat) string {
+	var lines []string
+	if stat.Requests > 0 {
+		line := fmt.Sprintf("%d 次请求，共 %d tokens（输入 %d，输出 %d", stat.Requests, stat.TotalTokens, stat.PromptTokens, stat.CompletionTokens)
+		if stat.ReasoningTokens > 0 {
+			line += fmt.Sprintf("，其中思考 %d", stat.ReasoningTokens)
+		}
+		lines = append(lines, line+"）")
+	}
+	if stat.EstimatedRequests > 0 {
+		lines = append(lines, fmt.Sprintf("%d 次请求为估算，约 %d tokens（输入 %d，输出 %d）",
+			stat.EstimatedRequests, stat.EstimatedUsage.TotalTokens, stat.EstimatedUsage.PromptTokens, stat.EstimatedUsage.CompletionTokens))
+	}
+	if len(lines) == 0 {
+		return "暂无记录"
+	}
+	return strings.Join(lines, "\n")
+}
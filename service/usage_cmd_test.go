@@ -0,0 +1,49 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/dal/cache"
+	"testing"
+)
+
+func TestFormatUsageStat(t *testing.T) {
+	tests := []struct {
+		name string
+		stat cache.UsageStat
+		want string
+	}{
+		{name: "empty", want: "暂无记录"},
+		{
+			name: "reported only",
+			stat: cache.UsageStat{Usage: ai.Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30}, Requests: 2},
+			want: "2 次请求，共 30 tokens（输入 10，输出 20）",
+		},
+		{
+			name: "reasoning tokens",
+			stat: cache.UsageStat{Usage: ai.Usage{PromptTokens: 10, CompletionTokens: 20, ReasoningTokens: 5, TotalTokens: 30}, Requests: 1},
+			want: "1 次请求，共 30 tokens（输入 10，输出 20，其中思考 5）",
+		},
+		{
+			name: "estimated listed separately",
+			stat: cache.UsageStat{
+				Usage:             ai.Usage{PromptTokens: 10, CompletionTokens: 20, TotalTokens: 30},
+				Requests:          1,
+				EstimatedUsage:    ai.Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7, Estimated: true},
+				EstimatedRequests: 1,
+			},
+			want: "1 次请求，共 30 tokens（输入 10，输出 20）\n1 次请求为估算，约 7 tokens（输入 3，输出 4）",
+		},
+		{
+			name: "estimated only",
+			stat: cache.UsageStat{EstimatedUsage: ai.Usage{PromptTokens: 3, CompletionTokens: 4, TotalTokens: 7}, EstimatedRequests: 2},
+			want: "2 次请求为估算，约 7 tokens（输入 3，输出 4）",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatUsageStat(tt.stat); got != tt.want {
+				t.Errorf("formatUsageStat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
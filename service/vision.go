@@ -0,0 +1,57 @@
+package service
+
+import (
+	"ai-stream-bot/client/ai"
+	"ai-stream-bot/client/im"
+	"ai-stream-bot/consts"
+	"ai-stream-bot/model"
+	"ai-stream-bot/pkg/feishu"
+	"fmt"
+
+	larkcard "github.com/larksuite/oapi-sdk-go/v3/card"
+)
+
+// defaultImagePrompt 只发送图片时使用的提问
+const defaultImagePrompt = "请描述这张图片的内容"
+
+// buildUserMessage 构建本轮用户消息，包含图片时下载图片并以多模态内容传递
+func buildUserMessage(action *model.MsgActionInfo, content string) (ai.AiMessage, error) {
+	msg := ai.AiMessage{Role: "user", Content: content}
+	imageKeys := action.ActionMsgInfo.ImageKeys
+	if len(imageKeys) == 0 {
+		return msg, nil
+	}
+	if msg.Content == "" {
+		msg.Content = defaultImagePrompt
+	}
+	msg.Parts = []ai.ContentPart{ai.NewTextPart(msg.Content)}
+	for _, key := range imageKeys {
+		data, err := im.GetFeishuClient().FeishuGetMessageResource(action.Ctx, *action.ActionMsgInfo.MsgId, key, "image", consts.MaxFileSize)
+		if err != nil {
+			return msg, fmt.Errorf("download image %s error: %w", key, err)
+		}
+		msg.Parts = append(msg.Parts, ai.NewImagePart(data))
+	}
+	return msg, nil
+}
+
+// buildVisionUnsupportedCard 构建模型不支持图片的提示卡片
+func buildVisionUnsupportedCard(provider, modelName string) *larkcard.MessageCard {
+	name := modelName
+	if provider != "" {
+		name = provider + "/" + modelName
+	}
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader("🖼️ 模型不支持图片", larkcard.TemplateOrange),
+		feishu.BuildCardNote(fmt.Sprintf("当前模型 %s 不支持图片理解", name)),
+		feishu.BuildCardNote("请通过 /model 切换到支持图片的模型后重新发送"),
+	)
+}
+
+// buildImageFailedCard 构建图片下载失败的提示卡片
+func buildImageFailedCard() *larkcard.MessageCard {
+	return feishu.BuildMessageCard(
+		feishu.BuildCardHeader("🖼️ 图片读取失败", larkcard.TemplateRed),
+		feishu.BuildCardNote("图片下载失败，请稍后重新发送"),
+	)
+}